@@ -43,4 +43,40 @@ type Accumulator interface {
 	SetPrecision(precision, interval time.Duration)
 
 	AddError(err error)
+
+	// WithTracking upgrades to a TrackingAccumulator with space for maxTracked
+	// metrics or metric groups to be in flight at the same time.
+	WithTracking(maxTracked int) TrackingAccumulator
+}
+
+// TrackingID uniquely identifies a tracked metric or metric group.
+type TrackingID uint64
+
+// DeliveryInfo reports the outcome of a tracked metric or metric group.
+type DeliveryInfo interface {
+	// ID is the TrackingID returned when the metric was added.
+	ID() TrackingID
+
+	// Delivered returns true if all outputs accepted the metric, false if
+	// any of them rejected it.
+	Delivered() bool
+}
+
+// TrackingAccumulator is an Accumulator that reports when the metrics added
+// to it have been written by the outputs, or discarded. Service inputs use it
+// to acknowledge messages only after the metrics parsed from them are safe.
+type TrackingAccumulator interface {
+	Accumulator
+
+	// AddTrackingMetric adds a metric and returns the ID its delivery will be
+	// reported with.
+	AddTrackingMetric(m Metric) TrackingID
+
+	// AddTrackingMetricGroup adds a group of metrics whose delivery is
+	// reported once, after all metrics in the group are done.
+	AddTrackingMetricGroup(group []Metric) TrackingID
+
+	// Delivered returns the channel delivery reports are sent on. It must be
+	// read from, or adding further tracked metrics will eventually block.
+	Delivered() <-chan DeliveryInfo
 }
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -23,6 +25,36 @@ type MetricMaker interface {
 	) telegraf.Metric
 }
 
+// errorLogger is implemented by makers that keep track of their errors.
+type errorLogger interface {
+	LogError(err error)
+}
+
+// processorMaker makes the metrics of a processor, which are passed on
+// unchanged. Processors use their accumulator to report errors.
+type processorMaker struct {
+	*models.RunningProcessor
+}
+
+func (p processorMaker) Name() string {
+	return "processors." + p.RunningProcessor.Name
+}
+
+func (p processorMaker) MakeMetric(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	mType telegraf.ValueType,
+	t time.Time,
+) telegraf.Metric {
+	m, err := metric.New(measurement, tags, fields, t, mType)
+	if err != nil {
+		log.Printf("E! Error adding point [%s]: %s\n", measurement, err.Error())
+		return nil
+	}
+	return m
+}
+
 func NewAccumulator(
 	maker MetricMaker,
 	metrics chan telegraf.Metric,
@@ -105,6 +137,9 @@ func (ac *accumulator) AddError(err error) {
 		return
 	}
 	NErrors.Incr(1)
+	if l, ok := ac.maker.(errorLogger); ok {
+		l.LogError(err)
+	}
 	//TODO suppress/throttle consecutive duplicate errors?
 	log.Printf("E! Error in plugin [%s]: %s", ac.maker.Name(), err)
 }
@@ -139,3 +174,56 @@ func (ac accumulator) getTime(t []time.Time) time.Time {
 	}
 	return timestamp.Round(ac.precision)
 }
+
+func (ac *accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return &trackingAccumulator{
+		accumulator: ac,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
+type trackingAccumulator struct {
+	*accumulator
+	delivered chan telegraf.DeliveryInfo
+}
+
+func (a *trackingAccumulator) AddTrackingMetric(m telegraf.Metric) telegraf.TrackingID {
+	dm := a.makeMetric(m)
+	if dm == nil {
+		// filtered out, so there is nothing left to deliver
+		_, id := metric.WithGroupTracking(nil, a.onDelivery)
+		return id
+	}
+	tm, id := metric.WithTracking(dm, a.onDelivery)
+	a.metrics <- tm
+	return id
+}
+
+func (a *trackingAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	mg := make([]telegraf.Metric, 0, len(group))
+	for _, m := range group {
+		if dm := a.makeMetric(m); dm != nil {
+			mg = append(mg, dm)
+		}
+	}
+	mg, id := metric.WithGroupTracking(mg, a.onDelivery)
+	for _, m := range mg {
+		a.metrics <- m
+	}
+	return id
+}
+
+func (a *trackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+// makeMetric passes the metric through the input's MetricMaker, the same way
+// as the Add* functions do.
+func (a *trackingAccumulator) makeMetric(m telegraf.Metric) telegraf.Metric {
+	return a.maker.MakeMetric(m.Name(), m.Fields(), m.Tags(), m.Type(),
+		a.getTime([]time.Time{m.Time()}))
+}
+
+func (a *trackingAccumulator) onDelivery(info telegraf.DeliveryInfo) {
+	a.delivered <- info
+}
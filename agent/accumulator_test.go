@@ -338,3 +338,52 @@ func (tm *TestMetricMaker) MakeMetric(
 	}
 	return nil
 }
+
+func TestAddTrackingMetric(t *testing.T) {
+	metrics := make(chan telegraf.Metric, 10)
+	defer close(metrics)
+	a := NewAccumulator(&TestMetricMaker{}, metrics).WithTracking(10)
+
+	m, err := metric.New("acctest",
+		map[string]string{},
+		map[string]interface{}{"value": float64(101)},
+		time.Now())
+	require.NoError(t, err)
+	id := a.AddTrackingMetric(m)
+
+	testm := <-metrics
+	assert.Contains(t, testm.String(), "acctest value=101")
+	select {
+	case <-a.Delivered():
+		t.Fatal("metric reported before it was written")
+	default:
+	}
+
+	testm.Accept()
+	info := <-a.Delivered()
+	assert.Equal(t, id, info.ID())
+	assert.True(t, info.Delivered())
+}
+
+func TestAddTrackingMetricGroupRejected(t *testing.T) {
+	metrics := make(chan telegraf.Metric, 10)
+	defer close(metrics)
+	a := NewAccumulator(&TestMetricMaker{}, metrics).WithTracking(10)
+
+	var group []telegraf.Metric
+	for _, name := range []string{"acctest1", "acctest2"} {
+		m, err := metric.New(name,
+			map[string]string{},
+			map[string]interface{}{"value": float64(101)},
+			time.Now())
+		require.NoError(t, err)
+		group = append(group, m)
+	}
+	id := a.AddTrackingMetricGroup(group)
+
+	(<-metrics).Accept()
+	(<-metrics).Reject()
+	info := <-a.Delivered()
+	assert.Equal(t, id, info.ID())
+	assert.False(t, info.Delivered())
+}
@@ -4,26 +4,44 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"reflect"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/config"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
 // Agent runs telegraf and collects data based on the given config
 type Agent struct {
 	Config *config.Config
+
+	// channels shared between all input threads for accumulating metrics,
+	// which are kept across reloads for the service inputs that keep running.
+	metricC chan telegraf.Metric
+	aggC    chan telegraf.Metric
+
+	// started holds the service inputs and outputs that have been started
+	// and not stopped yet.
+	started map[interface{}]bool
+
+	// running is set while all plugins are started and running.
+	running int32
 }
 
 // NewAgent returns an Agent struct based off the given Config
 func NewAgent(config *config.Config) (*Agent, error) {
 	a := &Agent{
-		Config: config,
+		Config:  config,
+		metricC: make(chan telegraf.Metric, 100),
+		aggC:    make(chan telegraf.Metric, 100),
+		started: make(map[interface{}]bool),
 	}
 
 	if !a.Config.Agent.OmitHostname {
@@ -42,9 +60,13 @@ func NewAgent(config *config.Config) (*Agent, error) {
 	return a, nil
 }
 
-// Connect connects to all configured outputs
+// Connect connects to all configured outputs that aren't connected yet
 func (a *Agent) Connect() error {
 	for _, o := range a.Config.Outputs {
+		if a.started[o] {
+			continue
+		}
+
 		switch ot := o.Output.(type) {
 		case telegraf.ServiceOutput:
 			if err := ot.Start(); err != nil {
@@ -54,6 +76,10 @@ func (a *Agent) Connect() error {
 			}
 		}
 
+		if err := o.OpenDiskBuffer(); err != nil {
+			return err
+		}
+
 		log.Printf("D! Attempting connection to output: %s\n", o.Name)
 		err := o.Output.Connect()
 		if err != nil {
@@ -66,6 +92,7 @@ func (a *Agent) Connect() error {
 			}
 		}
 		log.Printf("D! Successfully connected to output: %s\n", o.Name)
+		a.started[o] = true
 	}
 	return nil
 }
@@ -74,15 +101,93 @@ func (a *Agent) Connect() error {
 func (a *Agent) Close() error {
 	var err error
 	for _, o := range a.Config.Outputs {
-		err = o.Output.Close()
-		switch ot := o.Output.(type) {
-		case telegraf.ServiceOutput:
-			ot.Stop()
-		}
+		err = a.closeOutput(o)
 	}
 	return err
 }
 
+// Stop stops all service inputs and closes all outputs. It is called once
+// the agent is done running, and not on reload.
+func (a *Agent) Stop() error {
+	err := a.Close()
+	for _, input := range a.Config.Inputs {
+		a.stopInput(input)
+	}
+	return err
+}
+
+func (a *Agent) closeOutput(o *models.RunningOutput) error {
+	delete(a.started, o)
+	o.ReleaseStats()
+	err := o.Output.Close()
+	switch ot := o.Output.(type) {
+	case telegraf.ServiceOutput:
+		ot.Stop()
+	}
+	if berr := o.CloseDiskBuffer(); berr != nil {
+		log.Printf("E! Error closing disk buffer of output [%s]: %s\n",
+			o.Name, berr)
+	}
+	return err
+}
+
+func (a *Agent) stopInput(input *models.RunningInput) {
+	input.ReleaseStats()
+	if !a.started[input] {
+		return
+	}
+	delete(a.started, input)
+	if p, ok := input.Input.(telegraf.ServiceInput); ok {
+		p.Stop()
+	}
+}
+
+// Reload returns an agent running the new config c in place of a, which must
+// not be running anymore. Plugins whose configuration did not change are
+// taken over by the new agent, so that service inputs keep listening and
+// outputs keep their buffers and connections. All other plugins of a are
+// stopped. A change to the agent settings or global tags restarts all
+// plugins.
+func (a *Agent) Reload(c *config.Config) (*Agent, error) {
+	na, err := NewAgent(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if !reflect.DeepEqual(a.Config.Agent, c.Agent) ||
+		!reflect.DeepEqual(a.Config.Tags, c.Tags) {
+		log.Printf("I! Agent settings changed, restarting all plugins\n")
+		a.Stop()
+		return na, nil
+	}
+
+	// take over the channels the running service inputs write to
+	na.metricC = a.metricC
+	na.aggC = a.aggC
+	na.started = a.started
+
+	kept := c.Reuse(a.Config)
+
+	for _, input := range a.Config.Inputs {
+		if !kept[input] {
+			a.stopInput(input)
+		}
+	}
+	for _, o := range a.Config.Outputs {
+		if kept[o] {
+			continue
+		}
+		if err := a.closeOutput(o); err != nil {
+			log.Printf("E! Error closing output [%s]: %s\n", o.Name, err)
+		}
+	}
+
+	nPlugins := len(c.Inputs) + len(c.Outputs) + len(c.Processors) + len(c.Aggregators)
+	log.Printf("I! Reloaded config, kept %d of %d plugins running\n",
+		len(kept), nPlugins)
+	return na, nil
+}
+
 func panicRecover(input *models.RunningInput) {
 	if err := recover(); err != nil {
 		trace := make([]byte, 2048)
@@ -114,11 +219,43 @@ func (a *Agent) gatherer(
 	acc.SetPrecision(a.Config.Agent.Precision.Duration,
 		a.Config.Agent.Interval.Duration)
 
+	jitter := a.Config.Agent.CollectionJitter.Duration
+	// overwrite global jitter if this plugin has it's own.
+	if input.Config.CollectionJitter != 0 {
+		jitter = input.Config.CollectionJitter
+	}
+
+	if schedule := input.Config.Schedule; schedule != nil {
+		for {
+			next := schedule.Next(time.Now())
+			if next.IsZero() {
+				log.Printf("E! Schedule %q of input [%s] never runs\n",
+					schedule, input.Name())
+				return
+			}
+			if !sleepUntil(next, shutdown) {
+				return
+			}
+			internal.RandomSleep(jitter, shutdown)
+
+			start := time.Now()
+			// time out when the next run is due
+			gatherWithTimeout(shutdown, input, acc, schedule.Next(next).Sub(next))
+			GatherTime.Incr(time.Since(start).Nanoseconds())
+		}
+	}
+
+	if offset := input.Config.CollectionOffset; offset != 0 {
+		if !sleepUntil(alignTime(time.Now(), interval, offset), shutdown) {
+			return
+		}
+	}
+
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
-		internal.RandomSleep(a.Config.Agent.CollectionJitter.Duration, shutdown)
+		internal.RandomSleep(jitter, shutdown)
 
 		start := time.Now()
 		gatherWithTimeout(shutdown, input, acc, interval)
@@ -135,6 +272,28 @@ func (a *Agent) gatherer(
 	}
 }
 
+// alignTime returns the first time from now on that is a multiple of the
+// interval plus the offset.
+func alignTime(now time.Time, interval, offset time.Duration) time.Time {
+	t := now.Truncate(interval).Add(offset % interval)
+	for t.Before(now) {
+		t = t.Add(interval)
+	}
+	return t
+}
+
+// sleepUntil sleeps until t, returning false if shutdown is closed first.
+func sleepUntil(t time.Time, shutdown chan struct{}) bool {
+	timer := time.NewTimer(t.Sub(time.Now()))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-shutdown:
+		return false
+	}
+}
+
 // gatherWithTimeout gathers from the given input, with the given timeout.
 //   when the given timeout is reached, gatherWithTimeout logs an error message
 //   but continues waiting for it to return. This is to avoid leaving behind
@@ -150,7 +309,7 @@ func gatherWithTimeout(
 	defer ticker.Stop()
 	done := make(chan error)
 	go func() {
-		done <- input.Input.Gather(acc)
+		done <- input.Gather(acc)
 	}()
 
 	for {
@@ -208,7 +367,7 @@ func (a *Agent) Test() error {
 			fmt.Printf("* Internal: %s\n", input.Config.Interval)
 		}
 
-		if err := input.Input.Gather(acc); err != nil {
+		if err := input.Gather(acc); err != nil {
 			return err
 		}
 
@@ -218,7 +377,7 @@ func (a *Agent) Test() error {
 		case "inputs.cpu", "inputs.mongodb", "inputs.procstat":
 			time.Sleep(500 * time.Millisecond)
 			fmt.Printf("* Plugin: %s, Collection 2\n", input.Name())
-			if err := input.Input.Gather(acc); err != nil {
+			if err := input.Gather(acc); err != nil {
 				return err
 			}
 		}
@@ -235,10 +394,10 @@ func (a *Agent) flush() {
 	for _, o := range a.Config.Outputs {
 		go func(output *models.RunningOutput) {
 			defer wg.Done()
-			err := output.Write()
-			if err != nil {
-				log.Printf("E! Error writing to output [%s]: %s\n",
-					output.Name, err.Error())
+			// the last flush doesn't wait for outputs which are backing off
+			if err := output.WriteFinal(); err != nil {
+				log.Printf("E! Error writing to output [%s], %d metrics left "+
+					"unwritten: %s\n", output.Name, output.BufferLen(), err)
 			}
 		}(o)
 	}
@@ -246,6 +405,40 @@ func (a *Agent) flush() {
 	wg.Wait()
 }
 
+// flushOutput writes the metrics of a single output every interval, until
+// shutdown. Each output is flushed by its own goroutine, so that a slow
+// output doesn't hold up the others.
+func (a *Agent) flushOutput(
+	shutdown chan struct{},
+	output *models.RunningOutput,
+	interval time.Duration,
+	jitter time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdown:
+			return
+		case <-ticker.C:
+			// A write taking longer than the interval makes the ticker drop
+			// the ticks in between, and the output waits for a flush
+			// requested through the API, so flushes never overlap.
+			internal.RandomSleep(jitter, shutdown)
+			writeOutput(output)
+		}
+	}
+}
+
+func writeOutput(output *models.RunningOutput) {
+	err := output.Write()
+	if err != nil {
+		log.Printf("E! Error writing to output [%s]: %s\n",
+			output.Name, err.Error())
+	}
+}
+
 // flusher monitors the metrics input channel and flushes on the minimum interval
 func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, aggC chan telegraf.Metric) error {
 	// Inelegant, but this sleep is to allow the Gather threads to run, so that
@@ -268,25 +461,7 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 				}
 				return
 			case m := <-outMetricC:
-				// if dropOriginal is set to true, then we will only send this
-				// metric to the aggregators, not the outputs.
-				var dropOriginal bool
-				if !m.IsAggregate() {
-					for _, agg := range a.Config.Aggregators {
-						if ok := agg.Add(m.Copy()); ok {
-							dropOriginal = true
-						}
-					}
-				}
-				if !dropOriginal {
-					for i, o := range a.Config.Outputs {
-						if i == len(a.Config.Outputs)-1 {
-							o.AddMetric(m)
-						} else {
-							o.AddMetric(m.Copy())
-						}
-					}
-				}
+				a.routeMetric(m, shutdown)
 			}
 		}
 	}()
@@ -314,29 +489,47 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 		}
 	}()
 
-	ticker := time.NewTicker(a.Config.Agent.FlushInterval.Duration)
-	semaphore := make(chan struct{}, 1)
+	var flushWg sync.WaitGroup
+	flushWg.Add(len(a.Config.Outputs))
+	for _, o := range a.Config.Outputs {
+		interval := a.Config.Agent.FlushInterval.Duration
+		jitter := a.Config.Agent.FlushJitter.Duration
+		// overwrite the global flush interval and jitter if this output has
+		// its own.
+		if o.Config.FlushInterval != 0 {
+			interval = o.Config.FlushInterval
+		}
+		if o.Config.FlushJitter != 0 {
+			jitter = o.Config.FlushJitter
+		}
+		go func(output *models.RunningOutput, interv, jit time.Duration) {
+			defer flushWg.Done()
+			a.flushOutput(shutdown, output, interv, jit)
+		}(o, interval, jitter)
+	}
+
+	// processors holding on to metrics pass them on when they are due, not
+	// only when they receive further metrics.
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-shutdown:
 			log.Println("I! Hang on, flushing any cached metrics before shutdown")
-			// wait for outMetricC to get flushed before flushing outputs
+			// wait for outMetricC to get flushed, and for ongoing flushes to
+			// finish, before flushing outputs
 			wg.Wait()
+			for _, m := range a.flushProcessors(true) {
+				a.routeMetric(m, shutdown)
+			}
+			flushWg.Wait()
 			a.flush()
 			return nil
 		case <-ticker.C:
-			go func() {
-				select {
-				case semaphore <- struct{}{}:
-					internal.RandomSleep(a.Config.Agent.FlushJitter.Duration, shutdown)
-					a.flush()
-					<-semaphore
-				default:
-					// skipping this flush because one is already happening
-					log.Println("W! Skipping a scheduled flush because there is" +
-						" already a flush ongoing.")
-				}
-			}()
+			for _, m := range a.flushProcessors(false) {
+				outMetricC <- m
+			}
 		case metric := <-metricC:
 			// NOTE potential bottleneck here as we put each metric through the
 			// processors serially.
@@ -351,7 +544,59 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric, ag
 	}
 }
 
-// Run runs the agent daemon, gathering every Interval
+// routeMetric passes a processed metric on to the aggregators, and to the
+// outputs unless an aggregator drops the original.
+func (a *Agent) routeMetric(m telegraf.Metric, shutdown chan struct{}) {
+	// if dropOriginal is set to true, then we will only send this
+	// metric to the aggregators, not the outputs.
+	var dropOriginal bool
+	if !m.IsAggregate() {
+		for _, agg := range a.Config.Aggregators {
+			// aggregators keep their own copy, which doesn't hold up the
+			// delivery of a tracked metric.
+			if ok := agg.Add(metric.Unwrap(m).Copy()); ok {
+				dropOriginal = true
+			}
+		}
+	}
+	if dropOriginal {
+		m.Drop()
+		return
+	}
+
+	for i, o := range a.Config.Outputs {
+		// an output with the block overflow policy holds up all metrics
+		// until it has room, which backs up into the accumulators of the
+		// inputs.
+		o.WaitForSpace(shutdown)
+		if i == len(a.Config.Outputs)-1 {
+			o.AddMetric(m)
+		} else {
+			o.AddMetric(m.Copy())
+		}
+	}
+}
+
+// flushProcessors returns the metrics which processors held on to and which
+// are now due, or all of them if final is set, after applying the processors
+// that come after the one holding them.
+func (a *Agent) flushProcessors(final bool) []telegraf.Metric {
+	var out []telegraf.Metric
+	for i, processor := range a.Config.Processors {
+		metrics := processor.Flush(final)
+		if len(metrics) == 0 {
+			continue
+		}
+		for _, next := range a.Config.Processors[i+1:] {
+			metrics = next.Apply(metrics...)
+		}
+		out = append(out, metrics...)
+	}
+	return out
+}
+
+// Run runs the agent daemon, gathering every Interval, until shutdown is
+// closed. Plugins are left running, to be stopped by Stop or kept by Reload.
 func (a *Agent) Run(shutdown chan struct{}) error {
 	var wg sync.WaitGroup
 
@@ -360,17 +605,20 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 		a.Config.Agent.Interval.Duration, a.Config.Agent.Quiet,
 		a.Config.Agent.Hostname, a.Config.Agent.FlushInterval.Duration)
 
-	// channel shared between all input threads for accumulating metrics
-	metricC := make(chan telegraf.Metric, 100)
-	aggC := make(chan telegraf.Metric, 100)
+	metricC := a.metricC
+	aggC := a.aggC
 
 	now := time.Now()
 
-	// Start all ServicePlugins
+	// Start all ServicePlugins, except those still running from before a
+	// reload. They are stopped by Stop, or by the Reload that drops them.
 	for _, input := range a.Config.Inputs {
 		input.SetDefaultTags(a.Config.Tags)
 		switch p := input.Input.(type) {
 		case telegraf.ServiceInput:
+			if a.started[input] {
+				continue
+			}
 			acc := NewAccumulator(input, metricC)
 			// Service input plugins should set their own precision of their
 			// metrics.
@@ -380,7 +628,15 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 					input.Name(), err.Error())
 				return err
 			}
-			defer p.Stop()
+			a.started[input] = true
+		}
+	}
+
+	// Processors report errors through an accumulator of their own, like
+	// the inputs do.
+	for _, processor := range a.Config.Processors {
+		if p, ok := processor.Processor.(telegraf.ErrorReportingProcessor); ok {
+			p.SetAccumulator(NewAccumulator(processorMaker{processor}, metricC))
 		}
 	}
 
@@ -423,7 +679,8 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 		}(input, interval)
 	}
 
+	atomic.StoreInt32(&a.running, 1)
 	wg.Wait()
-	a.Close()
+	atomic.StoreInt32(&a.running, 0)
 	return nil
 }
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf/internal/config"
 
@@ -109,3 +110,19 @@ func TestAgent_LoadOutput(t *testing.T) {
 	a, _ = NewAgent(c)
 	assert.Equal(t, 3, len(a.Config.Outputs))
 }
+
+func TestAlignTime(t *testing.T) {
+	now := time.Date(2018, 1, 1, 10, 7, 30, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2018, 1, 1, 11, 5, 0, 0, time.UTC),
+		alignTime(now, time.Hour, 5*time.Minute))
+	assert.Equal(t, time.Date(2018, 1, 1, 10, 10, 0, 0, time.UTC),
+		alignTime(now, 5*time.Minute, 0))
+	assert.Equal(t, time.Date(2018, 1, 1, 10, 8, 0, 0, time.UTC),
+		alignTime(now, 5*time.Minute, 3*time.Minute))
+	// offsets larger than the interval wrap around
+	assert.Equal(t, time.Date(2018, 1, 1, 10, 8, 0, 0, time.UTC),
+		alignTime(now, 5*time.Minute, 8*time.Minute))
+	assert.Equal(t, time.Date(2018, 1, 1, 10, 9, 0, 0, time.UTC),
+		alignTime(now, 5*time.Minute, -time.Minute))
+}
@@ -0,0 +1,287 @@
+package agent
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// pluginInfo describes a loaded plugin. Only the settings common to all
+// plugins of a kind are listed, the plugin's own settings may hold secrets.
+type pluginInfo struct {
+	Name   string      `json:"name"`
+	Config interface{} `json:"config"`
+}
+
+type statInfo struct {
+	Name   string                 `json:"name"`
+	Tags   map[string]string      `json:"tags"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+type inputInfo struct {
+	Name          string     `json:"name"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+}
+
+type outputInfo struct {
+	Name        string  `json:"name"`
+	BufferSize  int     `json:"buffer_size"`
+	BufferLimit int     `json:"buffer_limit"`
+	BufferFill  float64 `json:"buffer_fill"`
+}
+
+// API is the HTTP management API. It is started once and kept across
+// reloads, serving whichever agent was set last.
+type API struct {
+	srv *http.Server
+
+	mu      sync.RWMutex
+	handler http.Handler
+}
+
+// StartAPI starts the management API on the given address. It returns once
+// the API is listening, and serves until it is closed. Until an agent is set,
+// all requests are answered with 503.
+func StartAPI(address string) (*API, error) {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	api := &API{}
+	api.srv = &http.Server{Handler: api}
+	go func() {
+		if err := api.srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("E! Error serving the API: %s\n", err)
+		}
+	}()
+	log.Printf("I! Serving the API on %s\n", ln.Addr())
+	return api, nil
+}
+
+// SetAgent makes the API serve the given agent, ie the one replacing the
+// previous agent on reload.
+func (api *API) SetAgent(a *Agent) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.handler = a.apiHandler()
+}
+
+// Close stops serving the API.
+func (api *API) Close() error {
+	return api.srv.Close()
+}
+
+func (api *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mu.RLock()
+	handler := api.handler
+	api.mu.RUnlock()
+	if handler == nil {
+		writeJSON(w, http.StatusServiceUnavailable,
+			map[string]string{"status": "starting"})
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+func (a *Agent) apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/plugins", get(a.servePlugins))
+	mux.HandleFunc("/stats", get(a.serveStats))
+	mux.HandleFunc("/inputs", get(a.serveInputs))
+	mux.HandleFunc("/outputs", get(a.serveOutputs))
+	mux.HandleFunc("/health", get(a.serveHealth))
+	mux.HandleFunc("/ready", get(a.serveReady))
+	mux.HandleFunc("/flush", post(a.serveFlush))
+	mux.HandleFunc("/gather", post(a.serveGather))
+	return mux
+}
+
+func get(h http.HandlerFunc) http.HandlerFunc {
+	return allowMethod("GET", h)
+}
+
+func post(h http.HandlerFunc) http.HandlerFunc {
+	return allowMethod("POST", h)
+}
+
+func allowMethod(method string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeJSON(w, http.StatusMethodNotAllowed,
+				map[string]string{"error": "method not allowed"})
+			return
+		}
+		h(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("E! Error writing API response: %s\n", err)
+	}
+}
+
+func (a *Agent) servePlugins(w http.ResponseWriter, r *http.Request) {
+	plugins := map[string][]pluginInfo{
+		"inputs":      {},
+		"outputs":     {},
+		"processors":  {},
+		"aggregators": {},
+	}
+	for _, input := range a.Config.Inputs {
+		plugins["inputs"] = append(plugins["inputs"],
+			pluginInfo{Name: input.Name(), Config: input.Config})
+	}
+	for _, o := range a.Config.Outputs {
+		plugins["outputs"] = append(plugins["outputs"],
+			pluginInfo{Name: outputName(o), Config: o.Config})
+	}
+	for _, p := range a.Config.Processors {
+		plugins["processors"] = append(plugins["processors"],
+			pluginInfo{Name: "processors." + p.Name, Config: p.Config})
+	}
+	for _, agg := range a.Config.Aggregators {
+		plugins["aggregators"] = append(plugins["aggregators"],
+			pluginInfo{Name: agg.Name(), Config: agg.Config})
+	}
+	writeJSON(w, http.StatusOK, plugins)
+}
+
+func (a *Agent) serveStats(w http.ResponseWriter, r *http.Request) {
+	stats := []statInfo{}
+	for _, m := range selfstat.Snapshot() {
+		stats = append(stats, statInfo{
+			Name:   m.Name(),
+			Tags:   m.Tags(),
+			Fields: m.Fields(),
+		})
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (a *Agent) serveInputs(w http.ResponseWriter, r *http.Request) {
+	inputs := []inputInfo{}
+	for _, input := range a.Config.Inputs {
+		info := inputInfo{Name: input.Name()}
+		if t, err := input.LastError(); err != nil {
+			info.LastError = err.Error()
+			info.LastErrorTime = &t
+		}
+		inputs = append(inputs, info)
+	}
+	writeJSON(w, http.StatusOK, inputs)
+}
+
+func (a *Agent) serveOutputs(w http.ResponseWriter, r *http.Request) {
+	outputs := []outputInfo{}
+	for _, o := range a.Config.Outputs {
+		outputs = append(outputs, outputInfo{
+			Name:        outputName(o),
+			BufferSize:  o.BufferLen(),
+			BufferLimit: o.MetricBufferLimit,
+			BufferFill:  o.BufferFill(),
+		})
+	}
+	writeJSON(w, http.StatusOK, outputs)
+}
+
+// serveHealth reports whether the agent is healthy, failing with the list of
+// health checks that failed.
+func (a *Agent) serveHealth(w http.ResponseWriter, r *http.Request) {
+	if failed := a.checkHealth(); len(failed) > 0 {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"status": "unhealthy",
+			"checks": failed,
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// serveReady reports whether all plugins are started and running.
+func (a *Agent) serveReady(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&a.running) == 0 {
+		writeJSON(w, http.StatusServiceUnavailable,
+			map[string]string{"status": "starting"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// serveFlush writes the buffered metrics of the output given by the name
+// parameter, or of all outputs if there is none. A flush of the output which
+// is in progress is waited for.
+func (a *Agent) serveFlush(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	var outputs []*models.RunningOutput
+	for _, o := range a.Config.Outputs {
+		if name == "" || outputName(o) == name {
+			outputs = append(outputs, o)
+		}
+	}
+	if len(outputs) == 0 {
+		writeJSON(w, http.StatusNotFound,
+			map[string]string{"error": "no output named " + name})
+		return
+	}
+
+	result := map[string]string{}
+	for _, o := range outputs {
+		result[outputName(o)] = "ok"
+		if err := o.Write(); err != nil {
+			result[outputName(o)] = err.Error()
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// serveGather gathers the inputs given by the name parameter once. The
+// metrics take the same way as those of a scheduled gather.
+func (a *Agent) serveGather(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	var inputs []*models.RunningInput
+	for _, input := range a.Config.Inputs {
+		if input.Name() == name {
+			inputs = append(inputs, input)
+		}
+	}
+	if len(inputs) == 0 {
+		writeJSON(w, http.StatusNotFound,
+			map[string]string{"error": "no input named " + name})
+		return
+	}
+
+	var errs []string
+	for _, input := range inputs {
+		acc := NewAccumulator(input, a.metricC)
+		acc.SetPrecision(a.Config.Agent.Precision.Duration,
+			a.Config.Agent.Interval.Duration)
+		if err := input.Gather(acc); err != nil {
+			acc.AddError(err)
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		writeJSON(w, http.StatusInternalServerError,
+			map[string]interface{}{"errors": errs})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func outputName(o *models.RunningOutput) string {
+	return "outputs." + o.Name
+}
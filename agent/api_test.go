@@ -0,0 +1,223 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type apiTestInput struct {
+	err error
+}
+
+func (i *apiTestInput) Description() string  { return "" }
+func (i *apiTestInput) SampleConfig() string { return "" }
+func (i *apiTestInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddFields("api_test", map[string]interface{}{"value": 1}, nil)
+	return i.err
+}
+
+type apiTestOutput struct {
+	metrics []telegraf.Metric
+	err     error
+}
+
+func (o *apiTestOutput) Connect() error       { return nil }
+func (o *apiTestOutput) Close() error         { return nil }
+func (o *apiTestOutput) Description() string  { return "" }
+func (o *apiTestOutput) SampleConfig() string { return "" }
+func (o *apiTestOutput) Write(metrics []telegraf.Metric) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.metrics = append(o.metrics, metrics...)
+	return nil
+}
+
+func newAPITestAgent(t *testing.T, input *apiTestInput, output *apiTestOutput) *Agent {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	c.Inputs = append(c.Inputs, models.NewRunningInput(input,
+		&models.InputConfig{Name: "api_test"}))
+	c.Outputs = append(c.Outputs, models.NewRunningOutput("api_test", output,
+		&models.OutputConfig{Name: "api_test"}, 10, 100))
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	return a
+}
+
+func apiRequest(t *testing.T, a *Agent, method, url string, v interface{}) int {
+	req := httptest.NewRequest(method, url, nil)
+	rec := httptest.NewRecorder()
+	a.apiHandler().ServeHTTP(rec, req)
+	if v != nil {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v))
+	}
+	return rec.Code
+}
+
+func TestAPIPlugins(t *testing.T) {
+	a := newAPITestAgent(t, &apiTestInput{}, &apiTestOutput{})
+
+	var plugins map[string][]pluginInfo
+	assert.Equal(t, http.StatusOK, apiRequest(t, a, "GET", "/plugins", &plugins))
+	require.Len(t, plugins["inputs"], 1)
+	assert.Equal(t, "inputs.api_test", plugins["inputs"][0].Name)
+	require.Len(t, plugins["outputs"], 1)
+	assert.Equal(t, "outputs.api_test", plugins["outputs"][0].Name)
+	assert.Len(t, plugins["processors"], 0)
+
+	assert.Equal(t, http.StatusMethodNotAllowed,
+		apiRequest(t, a, "POST", "/plugins", nil))
+}
+
+func TestAPIReady(t *testing.T) {
+	a := newAPITestAgent(t, &apiTestInput{}, &apiTestOutput{})
+
+	assert.Equal(t, http.StatusOK, apiRequest(t, a, "GET", "/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable,
+		apiRequest(t, a, "GET", "/ready", nil))
+	a.running = 1
+	assert.Equal(t, http.StatusOK, apiRequest(t, a, "GET", "/ready", nil))
+}
+
+func TestAPIReload(t *testing.T) {
+	api := &API{}
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+		return rec
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, get("/health").Code)
+
+	a := newAPITestAgent(t, &apiTestInput{}, &apiTestOutput{})
+	a.running = 1
+	api.SetAgent(a)
+	assert.Equal(t, http.StatusOK, get("/ready").Code)
+
+	// the agent replacing a on reload is not ready until it runs
+	b := newAPITestAgent(t, &apiTestInput{}, &apiTestOutput{})
+	api.SetAgent(b)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/ready").Code)
+	b.running = 1
+	assert.Equal(t, http.StatusOK, get("/ready").Code)
+}
+
+func TestAPIGatherAndFlush(t *testing.T) {
+	input := &apiTestInput{}
+	output := &apiTestOutput{}
+	a := newAPITestAgent(t, input, output)
+
+	assert.Equal(t, http.StatusNotFound,
+		apiRequest(t, a, "POST", "/gather?name=inputs.foo", nil))
+	assert.Equal(t, http.StatusOK,
+		apiRequest(t, a, "POST", "/gather?name=inputs.api_test", nil))
+	require.Len(t, a.metricC, 1)
+	a.Config.Outputs[0].AddMetric(<-a.metricC)
+
+	var outputs []outputInfo
+	apiRequest(t, a, "GET", "/outputs", &outputs)
+	require.Len(t, outputs, 1)
+	assert.Equal(t, 1, outputs[0].BufferSize)
+	assert.Equal(t, 100, outputs[0].BufferLimit)
+
+	assert.Equal(t, http.StatusOK, apiRequest(t, a, "POST", "/flush", nil))
+	assert.Len(t, output.metrics, 1)
+	apiRequest(t, a, "GET", "/outputs", &outputs)
+	assert.Equal(t, 0, outputs[0].BufferSize)
+}
+
+func TestAPIInputErrors(t *testing.T) {
+	input := &apiTestInput{err: errors.New("connection refused")}
+	a := newAPITestAgent(t, input, &apiTestOutput{})
+
+	var inputs []inputInfo
+	apiRequest(t, a, "GET", "/inputs", &inputs)
+	require.Len(t, inputs, 1)
+	assert.Empty(t, inputs[0].LastError)
+
+	assert.Equal(t, http.StatusInternalServerError,
+		apiRequest(t, a, "POST", "/gather?name=inputs.api_test", nil))
+	apiRequest(t, a, "GET", "/inputs", &inputs)
+	assert.Equal(t, "connection refused", inputs[0].LastError)
+	assert.NotNil(t, inputs[0].LastErrorTime)
+}
+
+func TestAPIHealthChecks(t *testing.T) {
+	input := &apiTestInput{err: errors.New("connection refused")}
+	output := &apiTestOutput{}
+	a := newAPITestAgent(t, input, output)
+	a.Config.Inputs[0].Config.HealthMaxGatherErrors = 2
+	a.Config.Outputs[0].Config.HealthMaxBufferFill = 50
+
+	assert.Equal(t, http.StatusOK, apiRequest(t, a, "GET", "/health", nil))
+
+	a.Config.Inputs[0].Gather(NewAccumulator(a.Config.Inputs[0], a.metricC))
+	assert.Equal(t, http.StatusOK, apiRequest(t, a, "GET", "/health", nil))
+	a.Config.Inputs[0].Gather(NewAccumulator(a.Config.Inputs[0], a.metricC))
+
+	var health struct {
+		Status string
+		Checks []string
+	}
+	assert.Equal(t, http.StatusServiceUnavailable,
+		apiRequest(t, a, "GET", "/health", &health))
+	assert.Equal(t, "unhealthy", health.Status)
+	assert.Equal(t, []string{"inputs.api_test: 2 gathers in a row failed"},
+		health.Checks)
+
+	input.err = nil
+	a.Config.Inputs[0].Gather(NewAccumulator(a.Config.Inputs[0], a.metricC))
+	assert.Equal(t, http.StatusOK, apiRequest(t, a, "GET", "/health", nil))
+
+	// fill the buffer of the output past half of its limit
+	output.err = errors.New("server unavailable")
+	for i := 0; i < 3; i++ {
+		a.Config.Outputs[0].AddMetric(<-a.metricC)
+	}
+	for i := 0; i < 60; i++ {
+		a.Config.Outputs[0].AddMetric(testutil.TestMetric(1))
+	}
+	assert.Equal(t, http.StatusServiceUnavailable,
+		apiRequest(t, a, "GET", "/health", &health))
+	require.Len(t, health.Checks, 1)
+	assert.Contains(t, health.Checks[0], "outputs.api_test: buffer is")
+}
+
+func TestAPIHealthChecksPerInput(t *testing.T) {
+	failing := &apiTestInput{err: errors.New("connection refused")}
+	a := newAPITestAgent(t, failing, &apiTestOutput{})
+	working := models.NewRunningInput(&apiTestInput{},
+		&models.InputConfig{Name: "api_test"})
+	a.Config.Inputs = append(a.Config.Inputs, working)
+	for _, input := range a.Config.Inputs {
+		input.Config.HealthMaxGatherErrors = 2
+	}
+
+	// gathers of the other input of the same name don't reset the count
+	for i := 0; i < 2; i++ {
+		a.Config.Inputs[0].Gather(NewAccumulator(a.Config.Inputs[0], a.metricC))
+		working.Gather(NewAccumulator(working, a.metricC))
+	}
+
+	var health struct {
+		Status string
+		Checks []string
+	}
+	assert.Equal(t, http.StatusServiceUnavailable,
+		apiRequest(t, a, "GET", "/health", &health))
+	assert.Equal(t, []string{"inputs.api_test: 2 gathers in a row failed"},
+		health.Checks)
+	assert.Equal(t, int64(2), a.Config.Inputs[0].ConsecutiveErrors.Get())
+	assert.Equal(t, int64(0), working.ConsecutiveErrors.Get())
+}
@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+)
+
+// checkHealth evaluates the health checks configured on the inputs and
+// outputs, and returns a description of each failing one.
+func (a *Agent) checkHealth() []string {
+	failed := []string{}
+	for _, o := range a.Config.Outputs {
+		if max := o.Config.HealthMaxFailureDuration; max > 0 {
+			since := o.FailingSince()
+			if !since.IsZero() && time.Since(since) > max {
+				failed = append(failed, fmt.Sprintf(
+					"%s: %d writes failed since %s, longer than %s",
+					outputName(o), o.WriteFailures.Get(),
+					since.Format(time.RFC3339), max))
+			}
+		}
+		if max := o.Config.HealthMaxBufferFill; max > 0 {
+			if fill := o.BufferFill() * 100; fill > max {
+				failed = append(failed, fmt.Sprintf(
+					"%s: buffer is %.1f%% full, more than %g%%",
+					outputName(o), fill, max))
+			}
+		}
+	}
+	for _, input := range a.Config.Inputs {
+		if max := input.Config.HealthMaxGatherErrors; max > 0 {
+			if n := input.ConsecutiveErrors.Get(); n >= int64(max) {
+				failed = append(failed, fmt.Sprintf(
+					"%s: %d gathers in a row failed", input.Name(), n))
+			}
+		}
+	}
+	return failed
+}
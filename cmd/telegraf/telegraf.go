@@ -123,6 +123,9 @@ func reloadLoop(
 	aggregatorFilters []string,
 	processorFilters []string,
 ) {
+	var ag *agent.Agent
+	var api *agent.API
+	var apiListen string
 	reload := make(chan bool, 1)
 	reload <- true
 	for <-reload {
@@ -160,7 +163,12 @@ func reloadLoop(
 				c.Agent.Interval.Duration)
 		}
 
-		ag, err := agent.NewAgent(c)
+		// On reload, plugins whose configuration didn't change keep running.
+		if ag == nil {
+			ag, err = agent.NewAgent(c)
+		} else {
+			ag, err = ag.Reload(c)
+		}
 		if err != nil {
 			log.Fatal("E! " + err.Error())
 		}
@@ -180,6 +188,24 @@ func reloadLoop(
 			os.Exit(0)
 		}
 
+		// The API keeps serving across reloads, unless its address changed.
+		if ag.Config.Agent.APIListen != apiListen {
+			if api != nil {
+				api.Close()
+				api = nil
+			}
+			apiListen = ag.Config.Agent.APIListen
+			if apiListen != "" {
+				api, err = agent.StartAPI(apiListen)
+				if err != nil {
+					log.Fatal("E! Unable to start the API: " + err.Error())
+				}
+			}
+		}
+		if api != nil {
+			api.SetAgent(ag)
+		}
+
 		err = ag.Connect()
 		if err != nil {
 			log.Fatal("E! " + err.Error())
@@ -230,6 +256,10 @@ func reloadLoop(
 
 		ag.Run(shutdown)
 	}
+	ag.Stop()
+	if api != nil {
+		api.Close()
+	}
 }
 
 func usageExit(rc int) {
@@ -0,0 +1,559 @@
+package filter
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Expression is a compiled boolean expression over a metric's name, tags,
+// fields and time, ie:
+//
+//   e, _ := CompileExpression(`tags.env == "prod" && fields.value > 0`)
+//   e.Eval("cpu", map[string]string{"env": "prod"},
+//       map[string]interface{}{"value": 1.5}, time.Now()) // true
+//
+// The operands are string, number and boolean literals, and the references
+// name, time, tags.<key>, fields.<key>, tags["<key>"] and fields["<key>"].
+// Operators are ==, !=, <, <=, >, >=, the regular expression matches =~ and
+// !~, and !, && and || with parentheses for grouping.
+//
+// A reference to a missing tag or field is only equal to another missing
+// value, so `tags.env != "prod"` is true for metrics without an env tag. On
+// its own, a reference is true if the tag or field exists, or for boolean
+// fields if it is true. Numbers of any type compare by value. time compares
+// to an RFC3339 string or to a number of seconds since the epoch.
+type Expression struct {
+	root node
+	src  string
+}
+
+// CompileExpression parses the expression, returning an error describing
+// the first syntax error it finds.
+func CompileExpression(expr string) (*Expression, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+	}
+	return &Expression{root: root, src: expr}, nil
+}
+
+// Eval returns true if the metric matches the expression.
+func (e *Expression) Eval(
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	t time.Time,
+) bool {
+	return truthy(e.root.eval(&exprMetric{
+		name:   name,
+		tags:   tags,
+		fields: fields,
+		time:   t,
+	}))
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.src
+}
+
+type exprMetric struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	time   time.Time
+}
+
+type node interface {
+	eval(m *exprMetric) interface{}
+}
+
+type literal struct {
+	v interface{}
+}
+
+func (n *literal) eval(m *exprMetric) interface{} { return n.v }
+
+type nameRef struct{}
+
+func (n *nameRef) eval(m *exprMetric) interface{} { return m.name }
+
+type timeRef struct{}
+
+func (n *timeRef) eval(m *exprMetric) interface{} { return m.time }
+
+type tagRef struct {
+	key string
+}
+
+func (n *tagRef) eval(m *exprMetric) interface{} {
+	if v, ok := m.tags[n.key]; ok {
+		return v
+	}
+	return nil
+}
+
+type fieldRef struct {
+	key string
+}
+
+func (n *fieldRef) eval(m *exprMetric) interface{} {
+	return m.fields[n.key]
+}
+
+type notNode struct {
+	n node
+}
+
+func (n *notNode) eval(m *exprMetric) interface{} {
+	return !truthy(n.n.eval(m))
+}
+
+type andNode struct {
+	l, r node
+}
+
+func (n *andNode) eval(m *exprMetric) interface{} {
+	return truthy(n.l.eval(m)) && truthy(n.r.eval(m))
+}
+
+type orNode struct {
+	l, r node
+}
+
+func (n *orNode) eval(m *exprMetric) interface{} {
+	return truthy(n.l.eval(m)) || truthy(n.r.eval(m))
+}
+
+type compareNode struct {
+	op   string
+	l, r node
+}
+
+func (n *compareNode) eval(m *exprMetric) interface{} {
+	return compare(n.op, n.l.eval(m), n.r.eval(m))
+}
+
+type matchNode struct {
+	n      node
+	re     *regexp.Regexp
+	negate bool
+}
+
+func (n *matchNode) eval(m *exprMetric) interface{} {
+	s, ok := n.n.eval(m).(string)
+	if !ok {
+		return n.negate
+	}
+	return n.re.MatchString(s) != n.negate
+}
+
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	default:
+		return true
+	}
+}
+
+func compare(op string, a, b interface{}) bool {
+	if a == nil || b == nil {
+		switch op {
+		case "==":
+			return a == nil && b == nil
+		case "!=":
+			return a != nil || b != nil
+		}
+		return false
+	}
+
+	if t, ok := a.(time.Time); ok {
+		a = t.UnixNano()
+		b = toUnixNano(b)
+	} else if t, ok := b.(time.Time); ok {
+		a = toUnixNano(a)
+		b = t.UnixNano()
+	}
+
+	var c int
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		if !ok {
+			return op == "!="
+		}
+		switch {
+		case x < y:
+			c = -1
+		case x > y:
+			c = 1
+		}
+	} else if x, ok := a.(string); ok {
+		y, ok := b.(string)
+		if !ok {
+			return op == "!="
+		}
+		c = strings.Compare(x, y)
+	} else if x, ok := a.(bool); ok {
+		y, ok := b.(bool)
+		if !ok {
+			return op == "!="
+		}
+		switch op {
+		case "==":
+			return x == y
+		case "!=":
+			return x != y
+		}
+		return false
+	} else {
+		return op == "!="
+	}
+
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// toUnixNano converts the operand time is compared to, returning nil if it
+// isn't a time.
+func toUnixNano(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil
+		}
+		return t.UnixNano()
+	}
+	if f, ok := toFloat(v); ok {
+		return f * float64(time.Second)
+	}
+	return nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var operators = []string{
+	"==", "!=", "<=", ">=", "=~", "!~", "&&", "||",
+	"<", ">", "!", "(", ")", "[", "]", "-",
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(s) {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			str, n, err := lexString(s[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%s at position %d", err, i)
+			}
+			tokens = append(tokens, token{tokString, str, i})
+			i += n
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(s) && (isIdentChar(rune(s[j])) || s[j] == '.' ||
+				((s[j] == '-' || s[j] == '+') && (s[j-1] == 'e' || s[j-1] == 'E'))) {
+				j++
+			}
+			tokens = append(tokens, token{tokNumber, s[i:j], i})
+			i = j
+		case isIdentChar(c):
+			j := i
+			for j < len(s) && (isIdentChar(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokIdent, s[i:j], i})
+			i = j
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+			}
+			tokens = append(tokens, token{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{tokEOF, "end of expression", len(s)}), nil
+}
+
+func isIdentChar(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// lexString reads the quoted string at the start of s, returning its
+// unquoted value and length. A backslash escapes the quote and itself, and is
+// kept before any other character, so that regular expressions like "\d+"
+// don't need to be escaped twice.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	var buf bytes.Buffer
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case quote:
+			return buf.String(), i + 1, nil
+		case '\\':
+			if i+1 < len(s) && (s[i+1] == quote || s[i+1] == '\\') {
+				i++
+			}
+		}
+		buf.WriteByte(s[i])
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return fmt.Errorf("expected %q at position %d, got %q", op, t.pos, t.text)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = &orNode{l, r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l = &andNode{l, r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept("!") {
+		n, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{n}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	l, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	if t.kind != tokOp {
+		return l, nil
+	}
+	switch t.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.next()
+		r, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{t.text, l, r}, nil
+	case "=~", "!~":
+		p.next()
+		pat := p.next()
+		if pat.kind != tokString {
+			return nil, fmt.Errorf("expected a regular expression string at position %d, got %q",
+				pat.pos, pat.text)
+		}
+		re, err := regexp.Compile(pat.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %d: %s",
+				pat.pos, err)
+		}
+		return &matchNode{l, re, t.text == "!~"}, nil
+	}
+	return l, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return &literal{t.text}, nil
+	case tokNumber:
+		return parseNumber(t)
+	case tokIdent:
+		return p.parseRef(t)
+	case tokOp:
+		switch t.text {
+		case "(":
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		case "-":
+			num := p.next()
+			if num.kind != tokNumber {
+				break
+			}
+			n, err := parseNumber(num)
+			if err != nil {
+				return nil, err
+			}
+			return &literal{-n.(*literal).v.(float64)}, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+}
+
+func parseNumber(t token) (node, error) {
+	f, err := strconv.ParseFloat(t.text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos)
+	}
+	return &literal{f}, nil
+}
+
+func (p *parser) parseRef(t token) (node, error) {
+	switch t.text {
+	case "true":
+		return &literal{true}, nil
+	case "false":
+		return &literal{false}, nil
+	case "name":
+		return &nameRef{}, nil
+	case "time":
+		return &timeRef{}, nil
+	case "tags", "fields":
+		// tags["key"] for keys that aren't identifiers
+		if err := p.expect("["); err != nil {
+			return nil, err
+		}
+		key := p.next()
+		if key.kind != tokString {
+			return nil, fmt.Errorf("expected a string key at position %d, got %q",
+				key.pos, key.text)
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return newRef(t.text, key.text), nil
+	}
+
+	if i := strings.IndexByte(t.text, '.'); i > 0 && i < len(t.text)-1 {
+		switch t.text[:i] {
+		case "tags", "fields":
+			return newRef(t.text[:i], t.text[i+1:]), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown reference %q at position %d, must be name, "+
+		"time, tags.<key> or fields.<key>", t.text, t.pos)
+}
+
+func newRef(kind, key string) node {
+	if kind == "tags" {
+		return &tagRef{key}
+	}
+	return &fieldRef{key}
+}
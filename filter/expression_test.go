@@ -0,0 +1,121 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	exprTags = map[string]string{
+		"env":       "prod",
+		"host":      "server01",
+		"host-role": "db",
+	}
+	exprFields = map[string]interface{}{
+		"value":   1.5,
+		"count":   int64(10),
+		"total":   uint64(20),
+		"status":  "ok",
+		"healthy": true,
+	}
+	exprTime = time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func TestExpressionEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`tags.env == "prod" && fields.value > 0`, true},
+		{`tags.env == "dev" || fields.value > 0`, true},
+		{`tags.env == 'dev' || fields.value > 2`, false},
+		{`name == "cpu"`, true},
+		{`name != "cpu"`, false},
+		{`!(name == "cpu")`, false},
+		{`tags["host-role"] == "db"`, true},
+		{`fields["value"] >= 1.5 && fields.value <= 1.5`, true},
+		{`fields.count == 10 && fields.total > fields.count`, true},
+		{`fields.count < -1`, false},
+		{`fields.value > 1e-3`, true},
+		{`fields.status == "ok"`, true},
+		{`fields.healthy`, true},
+		{`fields.healthy == false`, false},
+		{`tags.host =~ "^server\\d+$"`, true},
+		{`tags.host =~ "^server\d+$"`, true},
+		{`tags.host !~ "^server"`, false},
+		{`tags.host < "server02"`, true},
+		// missing tags and fields
+		{`tags.dc`, false},
+		{`tags.env`, true},
+		{`tags.dc == "east"`, false},
+		{`tags.dc != "east"`, true},
+		{`fields.missing > 0`, false},
+		{`fields.missing =~ ".*"`, false},
+		// mismatched types are never equal
+		{`fields.status == 1`, false},
+		{`fields.status != 1`, true},
+		{`fields.value > "a"`, false},
+		// time
+		{`time >= "2018-01-01T12:00:00Z"`, true},
+		{`time < "2018-01-01T00:00:00Z"`, false},
+		{`time > 1514808000`, false},
+		{`time == 1514808000`, true},
+		// precedence of && over ||
+		{`name == "mem" && tags.env == "dev" || fields.count == 10`, true},
+		{`name == "mem" && (tags.env == "dev" || fields.count == 10)`, false},
+	}
+
+	for _, tt := range tests {
+		e, err := CompileExpression(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, e.Eval("cpu", exprTags, exprFields, exprTime),
+			tt.expr)
+	}
+}
+
+func TestLexString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`"ok"`, `ok`},
+		{`'ok'`, `ok`},
+		{`"o\"k"`, `o"k`},
+		{`'o\'k'`, `o'k`},
+		{`"o'k"`, `o'k`},
+		{`"a\\b"`, `a\b`},
+		{`"\d+"`, `\d+`},
+		{`'\"'`, `\"`},
+	}
+
+	for _, tt := range tests {
+		s, n, err := lexString(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, s, tt.in)
+		assert.Equal(t, len(tt.in), n, tt.in)
+	}
+}
+
+func TestCompileExpressionErrors(t *testing.T) {
+	exprs := []string{
+		``,
+		`tags.env ==`,
+		`tags.env = "prod"`,
+		`(name == "cpu"`,
+		`name == "cpu")`,
+		`host == "server01"`,
+		`tags.env == "prod`,
+		`tags =~ "a"`,
+		`tags.env =~ "("`,
+		`tags.env =~ name`,
+		`fields.value > 10s`,
+	}
+
+	for _, expr := range exprs {
+		_, err := CompileExpression(expr)
+		assert.Error(t, err, expr)
+	}
+}
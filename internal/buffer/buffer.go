@@ -1,6 +1,7 @@
 package buffer
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/influxdata/telegraf"
@@ -12,10 +13,41 @@ var (
 	MetricsDropped = selfstat.Register("agent", "metrics_dropped", map[string]string{})
 )
 
+// OverflowPolicy decides what happens to metrics added to a full buffer.
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest evicts the oldest metrics to make room.
+	OverflowDropOldest OverflowPolicy = iota
+	// OverflowDropNewest drops the metrics being added.
+	OverflowDropNewest
+	// OverflowBlock is for callers that wait for room before adding. A
+	// Buffer still evicts the oldest metrics if it overflows, a DiskBuffer
+	// grows past its maximum size.
+	OverflowBlock
+)
+
+// ParseOverflowPolicy converts the configuration value of an overflow policy.
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch s {
+	case "", "drop-oldest":
+		return OverflowDropOldest, nil
+	case "drop-newest":
+		return OverflowDropNewest, nil
+	case "block":
+		return OverflowBlock, nil
+	default:
+		return OverflowDropOldest, fmt.Errorf("unknown buffer overflow policy %q,"+
+			" must be one of \"drop-oldest\", \"drop-newest\" or \"block\"", s)
+	}
+}
+
 // Buffer is an object for storing metrics in a circular buffer.
 type Buffer struct {
 	buf chan telegraf.Metric
 
+	policy OverflowPolicy
+
 	mu sync.Mutex
 }
 
@@ -45,15 +77,26 @@ func (b *Buffer) Add(metrics ...telegraf.Metric) {
 		select {
 		case b.buf <- metrics[i]:
 		default:
+			if b.policy == OverflowDropNewest {
+				MetricsDropped.Incr(1)
+				metrics[i].Reject()
+				continue
+			}
 			b.mu.Lock()
 			MetricsDropped.Incr(1)
-			<-b.buf
+			dropped := <-b.buf
+			dropped.Reject()
 			b.buf <- metrics[i]
 			b.mu.Unlock()
 		}
 	}
 }
 
+// SetOverflowPolicy sets what happens to metrics added to the full buffer.
+func (b *Buffer) SetOverflowPolicy(policy OverflowPolicy) {
+	b.policy = policy
+}
+
 // Batch returns a batch of metrics of size batchSize.
 // the batch will be of maximum length batchSize. It can be less than batchSize,
 // if the length of Buffer is less than batchSize.
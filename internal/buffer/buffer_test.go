@@ -70,6 +70,38 @@ func TestDroppingMetrics(t *testing.T) {
 	assert.Equal(t, int64(15), MetricsWritten.Get())
 }
 
+func TestDroppingNewestMetrics(t *testing.T) {
+	b := NewBuffer(5)
+	b.SetOverflowPolicy(OverflowDropNewest)
+	MetricsDropped.Set(0)
+
+	b.Add(metricList...)
+	b.Add(testutil.TestMetric(1, "mymetric6"))
+	assert.Equal(t, 5, b.Len())
+	assert.Equal(t, int64(1), MetricsDropped.Get())
+
+	batch := b.Batch(5)
+	assert.Equal(t, "mymetric1", batch[0].Name())
+	assert.Equal(t, "mymetric5", batch[4].Name())
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	p, err := ParseOverflowPolicy("")
+	assert.NoError(t, err)
+	assert.Equal(t, OverflowDropOldest, p)
+
+	p, err = ParseOverflowPolicy("drop-newest")
+	assert.NoError(t, err)
+	assert.Equal(t, OverflowDropNewest, p)
+
+	p, err = ParseOverflowPolicy("block")
+	assert.NoError(t, err)
+	assert.Equal(t, OverflowBlock, p)
+
+	_, err = ParseOverflowPolicy("drop-random")
+	assert.Error(t, err)
+}
+
 func TestGettingBatches(t *testing.T) {
 	b := NewBuffer(20)
 	MetricsDropped.Set(0)
@@ -0,0 +1,590 @@
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+const (
+	segmentExt = ".seg"
+	cursorFile = "cursor"
+
+	// Default maximum number of bytes kept on disk.
+	DEFAULT_DISK_MAX_SIZE = 100 * 1024 * 1024
+
+	// Default maximum size in bytes of a single segment file.
+	DEFAULT_DISK_SEGMENT_SIZE = 1024 * 1024
+)
+
+// FsyncPolicy controls when a DiskBuffer flushes its files to stable storage.
+type FsyncPolicy int
+
+const (
+	// FsyncSegment syncs a segment when it is rotated and when the buffer is
+	// closed.
+	FsyncSegment FsyncPolicy = iota
+	// FsyncAlways syncs after every call to Add and Remove.
+	FsyncAlways
+	// FsyncNever leaves flushing entirely to the operating system.
+	FsyncNever
+)
+
+// ParseFsyncPolicy converts the configuration value of an fsync policy.
+func ParseFsyncPolicy(s string) (FsyncPolicy, error) {
+	switch s {
+	case "", "segment":
+		return FsyncSegment, nil
+	case "always":
+		return FsyncAlways, nil
+	case "never":
+		return FsyncNever, nil
+	default:
+		return FsyncSegment, fmt.Errorf("unknown fsync policy %q, must be one of"+
+			" \"always\", \"segment\" or \"never\"", s)
+	}
+}
+
+// DiskBuffer is a segmented queue of metrics stored on disk, so that metrics
+// waiting to be written survive a restart of the agent.
+//
+// Metrics are appended to the newest segment file and read from the oldest.
+// Reading does not consume them: metrics returned by Peek stay on disk until
+// Remove is called, which makes it safe to only remove a batch once it has
+// been written to the output.
+type DiskBuffer struct {
+	dir         string
+	maxSize     int64
+	segmentSize int64
+	fsync       FsyncPolicy
+	policy      OverflowPolicy
+
+	mu sync.Mutex
+	// segments on disk, oldest first. The last segment is the one written to.
+	segments []*segment
+	head     *os.File
+	// offset is the read position within the oldest segment.
+	offset int64
+	// size is the total size of all segments in bytes.
+	size int64
+	// count is the number of records that have not been removed yet.
+	count int
+	// peeked holds the position after each record returned by the last Peek.
+	peeked []position
+}
+
+type segment struct {
+	id   uint64
+	size int64
+	// count is the number of records left in the segment.
+	count int
+}
+
+type position struct {
+	seg    uint64
+	offset int64
+	// records is the number of records consumed to reach this position,
+	// including any unreadable records that were skipped.
+	records int
+}
+
+// NewDiskBuffer opens the disk buffer stored in dir, creating it if it does
+// not exist yet. Metrics left over from a previous run are available again
+// through Peek.
+//   maxSize is the maximum number of bytes kept on disk. When it is exceeded
+//   the oldest segment is dropped.
+//   segmentSize is the size in bytes at which a new segment file is started.
+func NewDiskBuffer(
+	dir string,
+	maxSize int64,
+	segmentSize int64,
+	fsync FsyncPolicy,
+) (*DiskBuffer, error) {
+	if maxSize <= 0 {
+		maxSize = DEFAULT_DISK_MAX_SIZE
+	}
+	if segmentSize <= 0 {
+		segmentSize = DEFAULT_DISK_SEGMENT_SIZE
+	}
+	if segmentSize > maxSize {
+		segmentSize = maxSize
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+
+	b := &DiskBuffer{
+		dir:         dir,
+		maxSize:     maxSize,
+		segmentSize: segmentSize,
+		fsync:       fsync,
+	}
+	if err := b.open(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// open loads the existing segments and read cursor from disk.
+func (b *DiskBuffer) open() error {
+	files, err := ioutil.ReadDir(b.dir)
+	if err != nil {
+		return err
+	}
+	var ids []uint64
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	cursorSeg, cursorOffset := b.readCursor()
+	for _, id := range ids {
+		if id < cursorSeg {
+			// fully consumed before the last shutdown
+			os.Remove(b.segmentPath(id))
+			continue
+		}
+
+		var offset int64
+		if id == cursorSeg {
+			offset = cursorOffset
+		}
+		seg, offset, err := b.loadSegment(id, offset)
+		if err != nil {
+			return err
+		}
+		if len(b.segments) == 0 {
+			b.offset = offset
+		}
+		b.segments = append(b.segments, seg)
+		b.size += seg.size
+		b.count += seg.count
+	}
+
+	if len(b.segments) == 0 {
+		id := cursorSeg
+		if id == 0 {
+			id = 1
+		}
+		b.offset = 0
+		return b.createHead(id)
+	}
+
+	last := b.segments[len(b.segments)-1]
+	b.head, err = os.OpenFile(b.segmentPath(last.id), os.O_WRONLY|os.O_APPEND, 0640)
+	return err
+}
+
+// loadSegment stats a segment file, dropping any partially written record at
+// its end, and counts the records after offset. It returns the offset
+// actually used, which is reset to the start of the segment if it is invalid.
+func (b *DiskBuffer) loadSegment(id uint64, offset int64) (*segment, int64, error) {
+	path := b.segmentPath(id)
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size := int64(bytes.LastIndexByte(contents, '\n') + 1)
+	if size != int64(len(contents)) {
+		log.Printf("W! Truncating incomplete record at the end of %s", path)
+		if err := os.Truncate(path, size); err != nil {
+			return nil, 0, err
+		}
+	}
+	if offset > size {
+		offset = 0
+	}
+
+	seg := &segment{
+		id:    id,
+		size:  size,
+		count: bytes.Count(contents[offset:size], []byte{'\n'}),
+	}
+	return seg, offset, nil
+}
+
+// Len returns the number of metrics in the buffer.
+func (b *DiskBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count
+}
+
+// IsEmpty returns true if the buffer holds no metrics.
+func (b *DiskBuffer) IsEmpty() bool {
+	return b.Len() == 0
+}
+
+// Size returns the number of bytes the buffer occupies on disk.
+func (b *DiskBuffer) Size() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size
+}
+
+// MaxSize returns the maximum number of bytes the buffer keeps on disk.
+func (b *DiskBuffer) MaxSize() int64 {
+	return b.maxSize
+}
+
+// SetOverflowPolicy sets what happens when metrics are added to the full
+// buffer.
+func (b *DiskBuffer) SetOverflowPolicy(policy OverflowPolicy) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.policy = policy
+}
+
+// IsFull returns true if the buffer has reached its maximum size.
+func (b *DiskBuffer) IsFull() bool {
+	return b.Size() >= b.maxSize
+}
+
+// Add appends metrics to the buffer. If the buffer grows past its maximum
+// size, the oldest segment is dropped, unless the overflow policy says
+// otherwise. Metrics that don't fit under OverflowDropNewest are rejected.
+func (b *DiskBuffer) Add(metrics ...telegraf.Metric) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	head := b.segments[len(b.segments)-1]
+	var pending []byte
+	var pendingCount int
+	for _, m := range metrics {
+		MetricsWritten.Incr(1)
+		rec := encodeRecord(m)
+		if b.policy == OverflowDropNewest &&
+			b.size+int64(len(pending)+len(rec)) > b.maxSize {
+			MetricsDropped.Incr(1)
+			m.Reject()
+			continue
+		}
+		used := head.size + int64(len(pending))
+		if used > 0 && used+int64(len(rec)) > b.segmentSize {
+			if err := b.writeHead(head, pending, pendingCount); err != nil {
+				return err
+			}
+			pending, pendingCount = nil, 0
+			if err := b.rotate(); err != nil {
+				return err
+			}
+			head = b.segments[len(b.segments)-1]
+		}
+		pending = append(pending, rec...)
+		pendingCount++
+	}
+	if err := b.writeHead(head, pending, pendingCount); err != nil {
+		return err
+	}
+
+	for b.policy == OverflowDropOldest && b.size > b.maxSize && len(b.segments) > 1 {
+		b.dropOldest()
+	}
+
+	if b.fsync == FsyncAlways {
+		return b.head.Sync()
+	}
+	return nil
+}
+
+func (b *DiskBuffer) writeHead(head *segment, data []byte, count int) error {
+	if len(data) == 0 {
+		return nil
+	}
+	n, err := b.head.Write(data)
+	head.size += int64(n)
+	b.size += int64(n)
+	if err != nil {
+		return err
+	}
+	head.count += count
+	b.count += count
+	return nil
+}
+
+// Peek returns up to batchSize of the oldest metrics without removing them
+// from the buffer.
+func (b *DiskBuffer) Peek(batchSize int) ([]telegraf.Metric, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.peeked = b.peeked[:0]
+	out := make([]telegraf.Metric, 0, min(b.count, batchSize))
+	skipped := 0
+	offset := b.offset
+	for _, seg := range b.segments {
+		if len(out) == batchSize {
+			break
+		}
+		f, err := os.Open(b.segmentPath(seg.id))
+		if err != nil {
+			return out, err
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return out, err
+		}
+
+		r := bufio.NewReader(f)
+		for len(out) < batchSize && offset < seg.size {
+			line, err := r.ReadBytes('\n')
+			if err != nil {
+				break
+			}
+			offset += int64(len(line))
+			skipped++
+
+			m, err := decodeRecord(line)
+			if err != nil {
+				log.Printf("E! Skipping unreadable metric in %s: %s",
+					b.segmentPath(seg.id), err)
+				continue
+			}
+			out = append(out, m)
+			b.peeked = append(b.peeked, position{
+				seg:     seg.id,
+				offset:  offset,
+				records: skipped,
+			})
+			skipped = 0
+		}
+		f.Close()
+		offset = 0
+	}
+	return out, nil
+}
+
+// Remove removes the first n metrics returned by the last call to Peek from
+// the buffer.
+func (b *DiskBuffer) Remove(n int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if n > len(b.peeked) {
+		n = len(b.peeked)
+	}
+	if n == 0 {
+		return nil
+	}
+
+	for _, p := range b.peeked[:n] {
+		// records are always consumed from the oldest segment
+		for b.segments[0].id < p.seg {
+			if err := b.removeOldest(); err != nil {
+				return err
+			}
+		}
+		if b.segments[0].id != p.seg {
+			// the segment has been dropped since the call to Peek
+			continue
+		}
+		seg := b.segments[0]
+		records := p.records
+		if records > seg.count {
+			records = seg.count
+		}
+		seg.count -= records
+		b.count -= records
+		b.offset = p.offset
+	}
+	b.peeked = b.peeked[n:]
+
+	if b.segments[0].count == 0 && b.offset >= b.segments[0].size {
+		if len(b.segments) > 1 {
+			if err := b.removeOldest(); err != nil {
+				return err
+			}
+		} else if err := b.reset(); err != nil {
+			return err
+		}
+	}
+	return b.writeCursor()
+}
+
+// Close syncs and closes the buffer files.
+func (b *DiskBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.head == nil {
+		return nil
+	}
+	if b.fsync != FsyncNever {
+		if err := b.head.Sync(); err != nil {
+			return err
+		}
+	}
+	if err := b.writeCursor(); err != nil {
+		return err
+	}
+	err := b.head.Close()
+	b.head = nil
+	return err
+}
+
+// rotate closes the current head segment and starts a new one.
+func (b *DiskBuffer) rotate() error {
+	if b.fsync != FsyncNever {
+		if err := b.head.Sync(); err != nil {
+			return err
+		}
+	}
+	if err := b.head.Close(); err != nil {
+		return err
+	}
+	return b.createHead(b.segments[len(b.segments)-1].id + 1)
+}
+
+func (b *DiskBuffer) createHead(id uint64) error {
+	f, err := os.OpenFile(b.segmentPath(id),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	b.head = f
+	b.segments = append(b.segments, &segment{id: id})
+	return nil
+}
+
+// reset replaces a fully consumed head segment with an empty one.
+func (b *DiskBuffer) reset() error {
+	old := b.segments[0]
+	if err := b.head.Close(); err != nil {
+		return err
+	}
+	b.segments = b.segments[:0]
+	b.size -= old.size
+	b.offset = 0
+	if err := b.createHead(old.id + 1); err != nil {
+		return err
+	}
+	return os.Remove(b.segmentPath(old.id))
+}
+
+// removeOldest deletes the oldest segment once all its records are consumed.
+func (b *DiskBuffer) removeOldest() error {
+	seg := b.segments[0]
+	b.count -= seg.count
+	b.size -= seg.size
+	b.segments = b.segments[1:]
+	b.offset = 0
+	return os.Remove(b.segmentPath(seg.id))
+}
+
+// dropOldest deletes the oldest segment to make room, counting any records
+// left in it as dropped.
+func (b *DiskBuffer) dropOldest() {
+	seg := b.segments[0]
+	MetricsDropped.Incr(int64(seg.count))
+	log.Printf("W! Disk buffer %s is full, dropping %d metrics", b.dir, seg.count)
+	if err := b.removeOldest(); err != nil {
+		log.Printf("E! Unable to remove segment from disk buffer %s: %s", b.dir, err)
+	}
+	b.peeked = b.peeked[:0]
+	if err := b.writeCursor(); err != nil {
+		log.Printf("E! Unable to update disk buffer cursor %s: %s", b.dir, err)
+	}
+}
+
+func (b *DiskBuffer) segmentPath(id uint64) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%020d%s", id, segmentExt))
+}
+
+// readCursor returns the segment and offset reading was at when the buffer
+// was last used.
+func (b *DiskBuffer) readCursor() (uint64, int64) {
+	contents, err := ioutil.ReadFile(filepath.Join(b.dir, cursorFile))
+	if err != nil {
+		return 0, 0
+	}
+	parts := strings.Fields(string(contents))
+	if len(parts) != 2 {
+		return 0, 0
+	}
+	seg, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0
+	}
+	offset, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0
+	}
+	return seg, offset
+}
+
+// writeCursor persists the current read position, replacing the cursor file
+// atomically.
+func (b *DiskBuffer) writeCursor() error {
+	path := filepath.Join(b.dir, cursorFile)
+	f, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(f, "%d %d\n", b.segments[0].id, b.offset)
+	if b.fsync == FsyncAlways {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// encodeRecord serializes a metric as its value type followed by its line
+// protocol representation.
+func encodeRecord(m telegraf.Metric) []byte {
+	rec := strconv.AppendInt(nil, int64(m.Type()), 10)
+	rec = append(rec, ' ')
+	return append(rec, m.Serialize()...)
+}
+
+func decodeRecord(rec []byte) (telegraf.Metric, error) {
+	i := bytes.IndexByte(rec, ' ')
+	if i == -1 {
+		return nil, fmt.Errorf("missing metric type")
+	}
+	mType, err := strconv.Atoi(string(rec[:i]))
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := metric.Parse(rec[i+1:])
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) != 1 {
+		return nil, fmt.Errorf("expected 1 metric, found %d", len(metrics))
+	}
+
+	m := metrics[0]
+	if telegraf.ValueType(mType) == m.Type() {
+		return m, nil
+	}
+	return metric.New(m.Name(), m.Tags(), m.Fields(), m.Time(),
+		telegraf.ValueType(mType))
+}
@@ -0,0 +1,239 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sameSizeMetrics are metrics whose records all have the same size.
+var sameSizeMetrics = []telegraf.Metric{
+	testutil.TestMetric(1, "mymetric1"),
+	testutil.TestMetric(1, "mymetric2"),
+	testutil.TestMetric(1, "mymetric3"),
+	testutil.TestMetric(1, "mymetric4"),
+	testutil.TestMetric(1, "mymetric5"),
+}
+
+func newTestDiskBuffer(t *testing.T, maxSize, segmentSize int64) (*DiskBuffer, string) {
+	dir, err := ioutil.TempDir("", "telegraf-disk-buffer")
+	require.NoError(t, err)
+	b, err := NewDiskBuffer(dir, maxSize, segmentSize, FsyncSegment)
+	require.NoError(t, err)
+	return b, dir
+}
+
+func TestDiskBufferPeekRemove(t *testing.T) {
+	b, dir := newTestDiskBuffer(t, 0, 0)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	assert.True(t, b.IsEmpty())
+	require.NoError(t, b.Add(metricList...))
+	assert.Equal(t, 5, b.Len())
+
+	batch, err := b.Peek(3)
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "mymetric1", batch[0].Name())
+	assert.Equal(t, "mymetric3", batch[2].Name())
+
+	// peeking again without removing returns the same metrics
+	batch, err = b.Peek(3)
+	require.NoError(t, err)
+	assert.Equal(t, "mymetric1", batch[0].Name())
+	assert.Equal(t, 5, b.Len())
+
+	require.NoError(t, b.Remove(len(batch)))
+	assert.Equal(t, 2, b.Len())
+
+	batch, err = b.Peek(10)
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+	assert.Equal(t, "mymetric4", batch[0].Name())
+	assert.Equal(t, "mymetric5", batch[1].Name())
+
+	require.NoError(t, b.Remove(len(batch)))
+	assert.True(t, b.IsEmpty())
+	assert.Equal(t, int64(0), b.Size())
+}
+
+func TestDiskBufferReplay(t *testing.T) {
+	b, dir := newTestDiskBuffer(t, 0, 0)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, b.Add(metricList...))
+	batch, err := b.Peek(2)
+	require.NoError(t, err)
+	require.NoError(t, b.Remove(len(batch)))
+
+	// peeked but never removed, so it must survive the restart
+	_, err = b.Peek(1)
+	require.NoError(t, err)
+	require.NoError(t, b.Close())
+
+	b, err = NewDiskBuffer(dir, 0, 0, FsyncSegment)
+	require.NoError(t, err)
+	defer b.Close()
+
+	assert.Equal(t, 3, b.Len())
+	batch, err = b.Peek(10)
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "mymetric3", batch[0].Name())
+	assert.Equal(t, metricList[2].Fields(), batch[0].Fields())
+	assert.Equal(t, metricList[2].Tags(), batch[0].Tags())
+	assert.Equal(t, metricList[2].Time(), batch[0].Time())
+}
+
+func TestDiskBufferKeepsValueType(t *testing.T) {
+	b, dir := newTestDiskBuffer(t, 0, 0)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	m, err := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0), telegraf.Counter)
+	require.NoError(t, err)
+	require.NoError(t, b.Add(m))
+
+	batch, err := b.Peek(1)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	assert.Equal(t, telegraf.Counter, batch[0].Type())
+}
+
+func TestDiskBufferSegments(t *testing.T) {
+	recordSize := int64(len(encodeRecord(sameSizeMetrics[0])))
+	b, dir := newTestDiskBuffer(t, 100*recordSize, 2*recordSize)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	require.NoError(t, b.Add(sameSizeMetrics...))
+	require.NoError(t, b.Add(sameSizeMetrics...))
+	assert.Equal(t, 10, b.Len())
+
+	segments, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+	assert.Len(t, segments, 5)
+
+	batch, err := b.Peek(5)
+	require.NoError(t, err)
+	require.Len(t, batch, 5)
+	require.NoError(t, b.Remove(len(batch)))
+	assert.Equal(t, 5, b.Len())
+
+	// fully consumed segments are deleted
+	segments, err = filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+	assert.Len(t, segments, 3)
+
+	batch, err = b.Peek(10)
+	require.NoError(t, err)
+	require.Len(t, batch, 5)
+	assert.Equal(t, "mymetric1", batch[0].Name())
+}
+
+func TestDiskBufferDropsOldestSegment(t *testing.T) {
+	recordSize := int64(len(encodeRecord(sameSizeMetrics[0])))
+	b, dir := newTestDiskBuffer(t, 4*recordSize, 2*recordSize)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+	MetricsDropped.Set(0)
+
+	require.NoError(t, b.Add(sameSizeMetrics...))
+	assert.Equal(t, 3, b.Len())
+	assert.Equal(t, int64(2), MetricsDropped.Get())
+
+	batch, err := b.Peek(10)
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "mymetric3", batch[0].Name())
+}
+
+func TestDiskBufferDropsNewest(t *testing.T) {
+	recordSize := int64(len(encodeRecord(sameSizeMetrics[0])))
+	b, dir := newTestDiskBuffer(t, 4*recordSize, 2*recordSize)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+	b.SetOverflowPolicy(OverflowDropNewest)
+	MetricsDropped.Set(0)
+
+	require.NoError(t, b.Add(sameSizeMetrics...))
+	assert.Equal(t, 4, b.Len())
+	assert.Equal(t, int64(1), MetricsDropped.Get())
+	assert.True(t, b.IsFull())
+
+	batch, err := b.Peek(10)
+	require.NoError(t, err)
+	require.Len(t, batch, 4)
+	assert.Equal(t, "mymetric1", batch[0].Name())
+	assert.Equal(t, "mymetric4", batch[3].Name())
+}
+
+func TestDiskBufferBlockKeepsAll(t *testing.T) {
+	recordSize := int64(len(encodeRecord(sameSizeMetrics[0])))
+	b, dir := newTestDiskBuffer(t, 4*recordSize, 2*recordSize)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+	b.SetOverflowPolicy(OverflowBlock)
+	MetricsDropped.Set(0)
+
+	// the caller is expected to wait while the buffer is full, so nothing
+	// is dropped if it doesn't
+	require.NoError(t, b.Add(sameSizeMetrics...))
+	assert.Equal(t, 5, b.Len())
+	assert.Equal(t, int64(0), MetricsDropped.Get())
+	assert.True(t, b.IsFull())
+}
+
+func TestDiskBufferTruncatesPartialRecord(t *testing.T) {
+	b, dir := newTestDiskBuffer(t, 0, 0)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, b.Add(metricList[:2]...))
+	require.NoError(t, b.Close())
+
+	// simulate a crash in the middle of writing a record
+	f, err := os.OpenFile(b.segmentPath(1), os.O_WRONLY|os.O_APPEND, 0640)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("0 mymetric3,tag1=val"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b, err = NewDiskBuffer(dir, 0, 0, FsyncSegment)
+	require.NoError(t, err)
+	defer b.Close()
+	assert.Equal(t, 2, b.Len())
+
+	require.NoError(t, b.Add(testutil.TestMetric(1, "mymetric4")))
+	batch, err := b.Peek(10)
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "mymetric4", batch[2].Name())
+}
+
+func TestParseFsyncPolicy(t *testing.T) {
+	p, err := ParseFsyncPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, FsyncSegment, p)
+
+	p, err = ParseFsyncPolicy("always")
+	require.NoError(t, err)
+	assert.Equal(t, FsyncAlways, p)
+
+	p, err = ParseFsyncPolicy("never")
+	require.NoError(t, err)
+	assert.Equal(t, FsyncNever, p)
+
+	_, err = ParseFsyncPolicy("sometimes")
+	assert.Error(t, err)
+}
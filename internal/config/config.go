@@ -2,6 +2,7 @@ package config
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -18,6 +19,8 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/buffer"
+	"github.com/influxdata/telegraf/internal/cron"
 	"github.com/influxdata/telegraf/internal/models"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
@@ -56,6 +59,9 @@ type Config struct {
 	Aggregators []*models.RunningAggregator
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors models.RunningProcessors
+
+	// fingerprints of the tables each running plugin was built from
+	fingerprints map[interface{}]string
 }
 
 func NewConfig() *Config {
@@ -73,6 +79,7 @@ func NewConfig() *Config {
 		Processors:    make([]*models.RunningProcessor, 0),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
+		fingerprints:  make(map[interface{}]string),
 	}
 	return c
 }
@@ -139,6 +146,10 @@ type AgentConfig struct {
 	Quiet        bool
 	Hostname     string
 	OmitHostname bool
+
+	// APIListen is the address the HTTP management API listens on. The API
+	// is disabled if it is empty.
+	APIListen string `toml:"api_listen"`
 }
 
 // Inputs returns a list of strings of the configured inputs.
@@ -252,6 +263,11 @@ var header = `# Telegraf Configuration
   ## If set to true, do no set the "host" tag in the telegraf agent.
   omit_hostname = false
 
+  ## Address of the HTTP management API, which lists the loaded plugins and
+  ## their stats, and allows triggering flushes and gathers. Disabled if empty.
+  ## It has no authentication, so only listen on trusted interfaces.
+  # api_listen = "localhost:8099"
+
 
 ###############################################################################
 #                            OUTPUT PLUGINS                                   #
@@ -711,12 +727,131 @@ func parseFile(fpath string) (*ast.Table, error) {
 	return toml.Parse(contents)
 }
 
+// Fingerprint returns a digest of the configuration table the given running
+// plugin was built from. Plugins of the same type with equal fingerprints are
+// configured identically. It returns "" for plugins not built by c.
+func (c *Config) Fingerprint(plugin interface{}) string {
+	return c.fingerprints[plugin]
+}
+
+// Reuse replaces each plugin of c by a plugin of old that is configured
+// identically, if there is one, so that a reload can keep it running. It
+// returns the set of plugins of old that were reused.
+func (c *Config) Reuse(old *Config) map[interface{}]bool {
+	reused := make(map[interface{}]bool)
+	reuse := func(plugin interface{}, candidates []interface{}) interface{} {
+		fingerprint := c.fingerprints[plugin]
+		for _, o := range candidates {
+			if !reused[o] && old.fingerprints[o] == fingerprint {
+				reused[o] = true
+				delete(c.fingerprints, plugin)
+				c.fingerprints[o] = fingerprint
+				return o
+			}
+		}
+		return plugin
+	}
+
+	var inputs []interface{}
+	for _, input := range old.Inputs {
+		inputs = append(inputs, input)
+	}
+	for i, input := range c.Inputs {
+		c.Inputs[i] = reuse(input, inputs).(*models.RunningInput)
+	}
+
+	var outputs []interface{}
+	for _, output := range old.Outputs {
+		outputs = append(outputs, output)
+	}
+	for i, output := range c.Outputs {
+		c.Outputs[i] = reuse(output, outputs).(*models.RunningOutput)
+	}
+
+	var processors []interface{}
+	for _, processor := range old.Processors {
+		processors = append(processors, processor)
+	}
+	for i, processor := range c.Processors {
+		c.Processors[i] = reuse(processor, processors).(*models.RunningProcessor)
+	}
+
+	var aggregators []interface{}
+	for _, aggregator := range old.Aggregators {
+		aggregators = append(aggregators, aggregator)
+	}
+	for i, aggregator := range c.Aggregators {
+		c.Aggregators[i] = reuse(aggregator, aggregators).(*models.RunningAggregator)
+	}
+
+	return reused
+}
+
+// tableFingerprint digests the contents of a plugin table. Keys are sorted,
+// so the order of options in the file doesn't matter.
+func tableFingerprint(name string, tbl *ast.Table) string {
+	var buf bytes.Buffer
+	buf.WriteString(name)
+	writeTable(&buf, tbl)
+	return fmt.Sprintf("%x", sha256.Sum256(buf.Bytes()))
+}
+
+func writeTable(buf *bytes.Buffer, tbl *ast.Table) {
+	keys := make([]string, 0, len(tbl.Fields))
+	for k := range tbl.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf.WriteString("{")
+	for _, k := range keys {
+		fmt.Fprintf(buf, "%q=", k)
+		switch v := tbl.Fields[k].(type) {
+		case *ast.KeyValue:
+			writeValue(buf, v.Value)
+		case *ast.Table:
+			writeTable(buf, v)
+		case []*ast.Table:
+			buf.WriteString("[")
+			for _, t := range v {
+				writeTable(buf, t)
+			}
+			buf.WriteString("]")
+		}
+		buf.WriteString(";")
+	}
+	buf.WriteString("}")
+}
+
+func writeValue(buf *bytes.Buffer, value ast.Value) {
+	switch v := value.(type) {
+	case *ast.String:
+		fmt.Fprintf(buf, "%q", v.Value)
+	case *ast.Integer:
+		buf.WriteString(v.Value)
+	case *ast.Float:
+		buf.WriteString(v.Value)
+	case *ast.Boolean:
+		buf.WriteString(v.Value)
+	case *ast.Datetime:
+		buf.WriteString(v.Value)
+	case *ast.Array:
+		buf.WriteString("[")
+		for _, e := range v.Value {
+			writeValue(buf, e)
+			buf.WriteString(",")
+		}
+		buf.WriteString("]")
+	}
+}
+
 func (c *Config) addAggregator(name string, table *ast.Table) error {
 	creator, ok := aggregators.Aggregators[name]
 	if !ok {
 		return fmt.Errorf("Undefined but requested aggregator: %s", name)
 	}
 	aggregator := creator()
+	fingerprint := tableFingerprint("aggregators."+name, table)
 
 	conf, err := buildAggregator(name, table)
 	if err != nil {
@@ -727,7 +862,9 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 		return err
 	}
 
-	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf))
+	ra := models.NewRunningAggregator(aggregator, conf)
+	c.fingerprints[ra] = fingerprint
+	c.Aggregators = append(c.Aggregators, ra)
 	return nil
 }
 
@@ -737,6 +874,7 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested processor: %s", name)
 	}
 	processor := creator()
+	fingerprint := tableFingerprint("processors."+name, table)
 
 	processorConfig, err := buildProcessor(name, table)
 	if err != nil {
@@ -753,6 +891,7 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 		Config:    processorConfig,
 	}
 
+	c.fingerprints[rf] = fingerprint
 	c.Processors = append(c.Processors, rf)
 	return nil
 }
@@ -766,6 +905,7 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested output: %s", name)
 	}
 	output := creator()
+	fingerprint := tableFingerprint("outputs."+name, table)
 
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
@@ -787,8 +927,20 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		return err
 	}
 
+	batchSize := c.Agent.MetricBatchSize
+	bufferLimit := c.Agent.MetricBufferLimit
+	// overwrite the global batch size and buffer limit if this output has
+	// its own.
+	if outputConfig.MetricBatchSize != 0 {
+		batchSize = outputConfig.MetricBatchSize
+	}
+	if outputConfig.MetricBufferLimit != 0 {
+		bufferLimit = outputConfig.MetricBufferLimit
+	}
+
 	ro := models.NewRunningOutput(name, output, outputConfig,
-		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+		batchSize, bufferLimit)
+	c.fingerprints[ro] = fingerprint
 	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
@@ -807,6 +959,7 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested input: %s", name)
 	}
 	input := creator()
+	fingerprint := tableFingerprint("inputs."+name, table)
 
 	// If the input has a SetParser function, then this means it can accept
 	// arbitrary types of input, so build the parser and set it.
@@ -829,6 +982,7 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	}
 
 	rp := models.NewRunningInput(input, pluginConfig)
+	c.fingerprints[rp] = fingerprint
 	c.Inputs = append(c.Inputs, rp)
 	return nil
 }
@@ -1091,6 +1245,15 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 			}
 		}
 	}
+
+	if node, ok := tbl.Fields["metricpass"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				f.MetricPass = str.Value
+			}
+		}
+	}
+
 	if err := f.Compile(); err != nil {
 		return f, err
 	}
@@ -1105,6 +1268,7 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 	delete(tbl.Fields, "tagpass")
 	delete(tbl.Fields, "tagexclude")
 	delete(tbl.Fields, "taginclude")
+	delete(tbl.Fields, "metricpass")
 	return f, nil
 }
 
@@ -1159,11 +1323,68 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["schedule"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				schedule, err := cron.Parse(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				cp.Schedule = schedule
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["collection_jitter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				cp.CollectionJitter = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["collection_offset"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				cp.CollectionOffset = dur
+			}
+		}
+	}
+
+	if cp.Schedule != nil && (cp.Interval != 0 || cp.CollectionOffset != 0) {
+		return nil, fmt.Errorf("schedule can't be combined with interval or "+
+			"collection_offset for input plugins (%s).", name)
+	}
+
+	if node, ok := tbl.Fields["health_max_gather_errors"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				cp.HealthMaxGatherErrors = v
+			}
+		}
+	}
+
 	delete(tbl.Fields, "name_prefix")
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "interval")
 	delete(tbl.Fields, "tags")
+	delete(tbl.Fields, "schedule")
+	delete(tbl.Fields, "collection_jitter")
+	delete(tbl.Fields, "collection_offset")
+	delete(tbl.Fields, "health_max_gather_errors")
 	var err error
 	cp.Filter, err = buildFilter(tbl)
 	if err != nil {
@@ -1352,5 +1573,176 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 	if len(oc.Filter.FieldPass) > 0 {
 		oc.Filter.NamePass = oc.Filter.FieldPass
 	}
+
+	if node, ok := tbl.Fields["disk_buffer_dir"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.DiskBufferDir = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["disk_buffer_max_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				oc.DiskBufferMaxSize, err = strconv.ParseInt(integer.Value, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["disk_buffer_segment_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				oc.DiskBufferSegmentSize, err = strconv.ParseInt(integer.Value, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["disk_buffer_fsync"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.DiskBufferFsync, err = buffer.ParseFsyncPolicy(str.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_backoff"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.RetryBackoff, err = time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_backoff_max"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.RetryBackoffMax, err = time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_backoff_jitter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.RetryBackoffJitter, err = time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["flush_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.FlushInterval, err = time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["flush_jitter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.FlushJitter, err = time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["metric_batch_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.MetricBatchSize = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["metric_buffer_limit"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.MetricBufferLimit = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["health_max_failure_duration"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.HealthMaxFailureDuration, err = time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["health_max_buffer_fill"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			switch v := kv.Value.(type) {
+			case *ast.Integer:
+				oc.HealthMaxBufferFill, err = strconv.ParseFloat(v.Value, 64)
+			case *ast.Float:
+				oc.HealthMaxBufferFill, err = strconv.ParseFloat(v.Value, 64)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_overflow_policy"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.BufferOverflowPolicy, err = buffer.ParseOverflowPolicy(str.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	delete(tbl.Fields, "disk_buffer_dir")
+	delete(tbl.Fields, "disk_buffer_max_size")
+	delete(tbl.Fields, "disk_buffer_segment_size")
+	delete(tbl.Fields, "disk_buffer_fsync")
+	delete(tbl.Fields, "retry_backoff")
+	delete(tbl.Fields, "retry_backoff_max")
+	delete(tbl.Fields, "retry_backoff_jitter")
+	delete(tbl.Fields, "flush_interval")
+	delete(tbl.Fields, "flush_jitter")
+	delete(tbl.Fields, "metric_batch_size")
+	delete(tbl.Fields, "metric_buffer_limit")
+	delete(tbl.Fields, "buffer_overflow_policy")
+	delete(tbl.Fields, "health_max_failure_duration")
+	delete(tbl.Fields, "health_max_buffer_fill")
 	return oc, nil
 }
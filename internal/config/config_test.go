@@ -5,13 +5,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf/internal/buffer"
 	"github.com/influxdata/telegraf/internal/models"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/exec"
 	"github.com/influxdata/telegraf/plugins/inputs/memcached"
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
+	_ "github.com/influxdata/telegraf/plugins/outputs/discard"
 	"github.com/influxdata/telegraf/plugins/parsers"
 
+	"github.com/influxdata/toml"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -174,3 +177,146 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	assert.Equal(t, pConfig, c.Inputs[3].Config,
 		"Merged Testdata did not produce correct procstat metadata.")
 }
+
+func TestConfig_LoadOutputOverrides(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/output_overrides.toml")
+	assert.NoError(t, err)
+	assert.Len(t, c.Outputs, 2)
+
+	o := c.Outputs[0]
+	assert.Equal(t, 60*time.Second, o.Config.FlushInterval)
+	assert.Equal(t, 5*time.Second, o.Config.FlushJitter)
+	assert.Equal(t, 5000, o.MetricBatchSize)
+	assert.Equal(t, 50000, o.MetricBufferLimit)
+	assert.Equal(t, buffer.OverflowDropNewest, o.Config.BufferOverflowPolicy)
+
+	// without overrides the agent settings apply
+	o = c.Outputs[1]
+	assert.Equal(t, time.Duration(0), o.Config.FlushInterval)
+	assert.Equal(t, 1000, o.MetricBatchSize)
+	assert.Equal(t, 10000, o.MetricBufferLimit)
+}
+
+func TestConfig_LoadHealthChecks(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/health_checks.toml")
+	assert.NoError(t, err)
+	assert.Len(t, c.Inputs, 1)
+	assert.Len(t, c.Outputs, 2)
+
+	assert.Equal(t, 3, c.Inputs[0].Config.HealthMaxGatherErrors)
+	assert.Equal(t, 5*time.Minute, c.Outputs[0].Config.HealthMaxFailureDuration)
+	assert.Equal(t, 90.0, c.Outputs[0].Config.HealthMaxBufferFill)
+	assert.Equal(t, 75.5, c.Outputs[1].Config.HealthMaxBufferFill)
+}
+
+func TestConfig_LoadMetricPass(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/metricpass.toml")
+	assert.NoError(t, err)
+	assert.Len(t, c.Inputs, 1)
+	assert.Len(t, c.Outputs, 1)
+
+	f := c.Inputs[0].Config.Filter
+	assert.Equal(t, `tags.env == "prod" && fields.value > 0`, f.MetricPass)
+	assert.True(t, f.IsActive())
+	assert.Equal(t, `name =~ "^cpu"`, c.Outputs[0].Config.Filter.MetricPass)
+}
+
+func TestConfig_LoadInputSchedule(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/input_schedule.toml")
+	assert.NoError(t, err)
+	assert.Len(t, c.Inputs, 2)
+
+	ic := c.Inputs[0].Config
+	if assert.NotNil(t, ic.Schedule) {
+		assert.Equal(t, "0 */6 * * *", ic.Schedule.String())
+	}
+	assert.Equal(t, 30*time.Second, ic.CollectionJitter)
+
+	ic = c.Inputs[1].Config
+	assert.Nil(t, ic.Schedule)
+	assert.Equal(t, time.Hour, ic.Interval)
+	assert.Equal(t, 5*time.Minute, ic.CollectionOffset)
+}
+
+func TestConfig_LoadInputScheduleWithInterval(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/input_schedule_interval.toml")
+	assert.Error(t, err)
+}
+
+func TestConfig_Reuse(t *testing.T) {
+	old := NewConfig()
+	err := old.LoadConfig("./testdata/reload_before.toml")
+	assert.NoError(t, err)
+
+	c := NewConfig()
+	err = c.LoadConfig("./testdata/reload_after.toml")
+	assert.NoError(t, err)
+	assert.Len(t, c.Inputs, 3)
+	assert.Len(t, c.Outputs, 1)
+
+	reused := c.Reuse(old)
+	assert.Len(t, reused, 2)
+
+	// plugins of different types are loaded in no particular order
+	var memcached []*models.RunningInput
+	for _, input := range c.Inputs {
+		if input.Config.Name == "memcached" {
+			memcached = append(memcached, input)
+		}
+	}
+	assert.Len(t, memcached, 2)
+
+	// the unchanged input and output are taken over, the rest is new
+	assert.True(t, reused[old.Inputs[0]])
+	assert.False(t, reused[old.Inputs[1]])
+	assert.True(t, reused[old.Outputs[0]])
+	assert.True(t, memcached[0] == old.Inputs[0])
+	assert.True(t, memcached[1] != old.Inputs[1])
+	assert.True(t, c.Outputs[0] == old.Outputs[0])
+
+	// reused plugins can be matched again on the next reload
+	assert.Equal(t, old.Fingerprint(old.Inputs[0]), c.Fingerprint(memcached[0]))
+	next := NewConfig()
+	err = next.LoadConfig("./testdata/reload_after.toml")
+	assert.NoError(t, err)
+	assert.Len(t, next.Reuse(c), 4)
+}
+
+func TestConfig_FingerprintIgnoresOrder(t *testing.T) {
+	a, err := toml.Parse([]byte(`
+servers = ["localhost"]
+interval = "5s"
+[tagpass]
+  cpu = ["cpu0"]
+`))
+	assert.NoError(t, err)
+	b, err := toml.Parse([]byte(`
+interval = "5s"
+servers = ["localhost"]
+[tagpass]
+  cpu = ["cpu0"]
+`))
+	assert.NoError(t, err)
+	c, err := toml.Parse([]byte(`
+interval = "5s"
+servers = ["localhost"]
+[tagpass]
+  cpu = ["cpu1"]
+`))
+	assert.NoError(t, err)
+
+	assert.Equal(t,
+		tableFingerprint("inputs.memcached", a),
+		tableFingerprint("inputs.memcached", b))
+	assert.NotEqual(t,
+		tableFingerprint("inputs.memcached", a),
+		tableFingerprint("inputs.memcached", c))
+	assert.NotEqual(t,
+		tableFingerprint("inputs.memcached", a),
+		tableFingerprint("inputs.redis", a))
+}
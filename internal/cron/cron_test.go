@@ -0,0 +1,84 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04:05", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		spec string
+		from string
+		next string
+	}{
+		{"* * * * *", "2018-01-01 10:00:30", "2018-01-01 10:01:00"},
+		{"* * * * *", "2018-01-01 10:00:00", "2018-01-01 10:01:00"},
+		{"0 */6 * * *", "2018-01-01 10:00:00", "2018-01-01 12:00:00"},
+		{"0 */6 * * *", "2018-01-01 23:59:00", "2018-01-02 00:00:00"},
+		{"30 2 * * *", "2018-01-01 03:00:00", "2018-01-02 02:30:00"},
+		{"5/15 * * * *", "2018-01-01 10:21:00", "2018-01-01 10:35:00"},
+		{"0 9-17/4 * * *", "2018-01-01 10:00:00", "2018-01-01 13:00:00"},
+		{"0,30 * * * *", "2018-01-01 10:10:00", "2018-01-01 10:30:00"},
+		{"0 0 1 * *", "2018-01-15 00:00:00", "2018-02-01 00:00:00"},
+		{"0 0 * * mon", "2018-01-03 00:00:00", "2018-01-08 00:00:00"},
+		{"0 0 * * 7", "2018-01-03 00:00:00", "2018-01-07 00:00:00"},
+		{"0 0 * jun *", "2018-01-03 00:00:00", "2018-06-01 00:00:00"},
+		{"0 0 29 2 *", "2018-01-01 00:00:00", "2020-02-29 00:00:00"},
+		// either day field matches when both are restricted
+		{"0 0 15 * fri", "2018-01-06 00:00:00", "2018-01-12 00:00:00"},
+		{"@hourly", "2018-01-01 10:10:00", "2018-01-01 11:00:00"},
+		{"@daily", "2018-01-01 10:10:00", "2018-01-02 00:00:00"},
+		{"@weekly", "2018-01-01 10:10:00", "2018-01-07 00:00:00"},
+	}
+
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		require.NoError(t, err, tt.spec)
+		assert.Equal(t, date(tt.next), s.Next(date(tt.from)), tt.spec)
+	}
+}
+
+func TestNextNever(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(date("2018-01-01 00:00:00")).IsZero())
+}
+
+func TestParseErrors(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@sometimes",
+	}
+
+	for _, spec := range specs {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestString(t *testing.T) {
+	s, err := Parse("0 */6 * * *")
+	require.NoError(t, err)
+	assert.Equal(t, "0 */6 * * *", s.String())
+}
@@ -155,3 +155,11 @@ func TestDuration(t *testing.T) {
 	d.UnmarshalTOML([]byte(`1.5`))
 	assert.Equal(t, time.Second, d.Duration)
 }
+
+func TestRandomDuration(t *testing.T) {
+	assert.Equal(t, time.Duration(0), RandomDuration(0))
+	for i := 0; i < 10; i++ {
+		d := RandomDuration(time.Second)
+		assert.True(t, d >= 0 && d < time.Second)
+	}
+}
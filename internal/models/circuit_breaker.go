@@ -0,0 +1,164 @@
+package models
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Default upper limit of the delay between retries of a failing output.
+const DEFAULT_RETRY_BACKOFF_MAX = 5 * time.Minute
+
+// CircuitState is the state of the circuit breaker guarding an output.
+type CircuitState int64
+
+const (
+	// CircuitClosed lets all writes through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen skips writes until the retry delay has passed.
+	CircuitOpen
+	// CircuitHalfOpen lets trial writes through after the delay.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker keeps track of failed writes to an output and decides when
+// the next write may be attempted. After each consecutive failure the retry
+// delay doubles, starting at backoff and capped at maxBackoff, plus a random
+// jitter. A zero backoff disables the breaker, so that every write is
+// attempted.
+type circuitBreaker struct {
+	backoff    time.Duration
+	maxBackoff time.Duration
+	jitter     time.Duration
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int64
+	retryAt  time.Time
+	// failingSince is the time of the first of the consecutive failures.
+	failingSince time.Time
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+func newCircuitBreaker(backoff, maxBackoff, jitter time.Duration) *circuitBreaker {
+	if maxBackoff == 0 {
+		maxBackoff = DEFAULT_RETRY_BACKOFF_MAX
+	}
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+	return &circuitBreaker{
+		backoff:    backoff,
+		maxBackoff: maxBackoff,
+		jitter:     jitter,
+		now:        time.Now,
+	}
+}
+
+// Allow returns true if a write should be attempted. Once the retry delay of
+// an open breaker has passed, Allow half-opens it until the outcome of the
+// next write closes or reopens it.
+func (cb *circuitBreaker) Allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == CircuitOpen {
+		if cb.now().Before(cb.retryAt) {
+			return false
+		}
+		cb.state = CircuitHalfOpen
+	}
+	return true
+}
+
+// Closed returns true if writes are going through normally.
+func (cb *circuitBreaker) Closed() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state == CircuitClosed
+}
+
+// Success records a successful write and closes the breaker.
+func (cb *circuitBreaker) Success() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.state = CircuitClosed
+	cb.failures = 0
+	cb.failingSince = time.Time{}
+}
+
+// Failure records a failed write. Unless the breaker is disabled it opens
+// the breaker and returns how long to wait before the next attempt.
+func (cb *circuitBreaker) Failure() time.Duration {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	if cb.failures == 1 {
+		cb.failingSince = cb.now()
+	}
+	if cb.backoff == 0 {
+		return 0
+	}
+
+	delay := cb.backoff
+	for i := int64(1); i < cb.failures && delay < cb.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > cb.maxBackoff {
+		delay = cb.maxBackoff
+	}
+	delay += internal.RandomDuration(cb.jitter)
+
+	cb.state = CircuitOpen
+	cb.retryAt = cb.now().Add(delay)
+	return delay
+}
+
+// State returns the current state of the breaker.
+func (cb *circuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// Failures returns the number of consecutive failed writes.
+func (cb *circuitBreaker) Failures() int64 {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.failures
+}
+
+// RetryIn returns the time left until an open breaker allows a write again.
+func (cb *circuitBreaker) RetryIn() time.Duration {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state != CircuitOpen {
+		return 0
+	}
+	return cb.retryAt.Sub(cb.now())
+}
+
+// FailingSince returns the time of the first of the consecutive failed
+// writes, or the zero time if the last write succeeded.
+func (cb *circuitBreaker) FailingSince() time.Time {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.failingSince
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	cb := newCircuitBreaker(0, 0, 0)
+
+	assert.Equal(t, time.Duration(0), cb.Failure())
+	assert.Equal(t, time.Duration(0), cb.Failure())
+	assert.True(t, cb.Allow())
+	assert.Equal(t, CircuitClosed, cb.State())
+	assert.Equal(t, int64(2), cb.Failures())
+}
+
+func TestCircuitBreakerBackoff(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	cb := newCircuitBreaker(time.Second, 5*time.Second, 0)
+	cb.now = clock.now
+
+	assert.Equal(t, time.Second, cb.Failure())
+	assert.Equal(t, CircuitOpen, cb.State())
+	assert.False(t, cb.Allow())
+	assert.False(t, cb.Closed())
+
+	clock.t = clock.t.Add(time.Second)
+	assert.True(t, cb.Allow())
+	assert.Equal(t, CircuitHalfOpen, cb.State())
+
+	// the delay doubles with each consecutive failure, up to the maximum
+	assert.Equal(t, 2*time.Second, cb.Failure())
+	clock.t = clock.t.Add(2 * time.Second)
+	assert.True(t, cb.Allow())
+	assert.Equal(t, 4*time.Second, cb.Failure())
+	clock.t = clock.t.Add(4 * time.Second)
+	assert.True(t, cb.Allow())
+	assert.Equal(t, 5*time.Second, cb.Failure())
+	assert.Equal(t, int64(4), cb.Failures())
+
+	clock.t = clock.t.Add(5 * time.Second)
+	assert.True(t, cb.Allow())
+	cb.Success()
+	assert.Equal(t, CircuitClosed, cb.State())
+	assert.Equal(t, int64(0), cb.Failures())
+	assert.Equal(t, time.Second, cb.Failure())
+}
+
+func TestCircuitBreakerJitter(t *testing.T) {
+	cb := newCircuitBreaker(time.Second, time.Second, time.Second)
+
+	for i := 0; i < 10; i++ {
+		delay := cb.Failure()
+		assert.True(t, delay >= time.Second && delay < 2*time.Second)
+	}
+}
+
+func TestCircuitStateString(t *testing.T) {
+	assert.Equal(t, "closed", CircuitClosed.String())
+	assert.Equal(t, "open", CircuitOpen.String())
+	assert.Equal(t, "half-open", CircuitHalfOpen.String())
+}
+
+func TestCircuitBreakerFailingSince(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	cb := newCircuitBreaker(0, 0, 0)
+	cb.now = clock.now
+
+	assert.True(t, cb.FailingSince().IsZero())
+	cb.Failure()
+	clock.t = clock.t.Add(time.Minute)
+	cb.Failure()
+	assert.Equal(t, time.Unix(0, 0), cb.FailingSince())
+
+	cb.Success()
+	assert.True(t, cb.FailingSince().IsZero())
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/influxdata/telegraf/filter"
 )
@@ -33,6 +34,11 @@ type Filter struct {
 	TagInclude []string
 	tagInclude filter.Filter
 
+	// MetricPass is a boolean expression over the name, tags, fields and
+	// time that metrics must match to pass.
+	MetricPass string
+	metricPass *filter.Expression
+
 	isActive bool
 }
 
@@ -45,7 +51,8 @@ func (f *Filter) Compile() error {
 		len(f.TagInclude) == 0 &&
 		len(f.TagExclude) == 0 &&
 		len(f.TagPass) == 0 &&
-		len(f.TagDrop) == 0 {
+		len(f.TagDrop) == 0 &&
+		f.MetricPass == "" {
 		return nil
 	}
 
@@ -90,17 +97,26 @@ func (f *Filter) Compile() error {
 			return fmt.Errorf("Error compiling 'tagpass', %s", err)
 		}
 	}
+
+	if f.MetricPass != "" {
+		f.metricPass, err = filter.CompileExpression(f.MetricPass)
+		if err != nil {
+			return fmt.Errorf("Error compiling 'metricpass', %s", err)
+		}
+	}
 	return nil
 }
 
 // Apply applies the filter to the given measurement name, fields map, and
 // tags map. It will return false if the metric should be "filtered out", and
-// true if the metric should "pass".
+// true if the metric should "pass". The time t of the metric is only used by
+// the metricpass expression.
 // It will modify tags & fields in-place if they need to be deleted.
 func (f *Filter) Apply(
 	measurement string,
 	fields map[string]interface{},
 	tags map[string]string,
+	t time.Time,
 ) bool {
 	if !f.isActive {
 		return true
@@ -116,6 +132,14 @@ func (f *Filter) Apply(
 		return false
 	}
 
+	// check if the metric matches the expression, before any fields or tags
+	// are removed
+	if f.metricPass != nil {
+		if !f.metricPass.Eval(measurement, tags, fields, t) {
+			return false
+		}
+	}
+
 	// filter fields
 	for fieldkey, _ := range fields {
 		if !f.shouldFieldPass(fieldkey) {
@@ -2,6 +2,7 @@ package models
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -12,7 +13,8 @@ func TestFilter_ApplyEmpty(t *testing.T) {
 	require.NoError(t, f.Compile())
 	assert.False(t, f.IsActive())
 
-	assert.True(t, f.Apply("m", map[string]interface{}{"value": int64(1)}, map[string]string{}))
+	assert.True(t, f.Apply("m", map[string]interface{}{"value": int64(1)}, map[string]string{},
+		time.Now()))
 }
 
 func TestFilter_ApplyTagsDontPass(t *testing.T) {
@@ -31,7 +33,7 @@ func TestFilter_ApplyTagsDontPass(t *testing.T) {
 
 	assert.False(t, f.Apply("m",
 		map[string]interface{}{"value": int64(1)},
-		map[string]string{"cpu": "cpu-total"}))
+		map[string]string{"cpu": "cpu-total"}, time.Now()))
 }
 
 func TestFilter_ApplyDeleteFields(t *testing.T) {
@@ -43,7 +45,7 @@ func TestFilter_ApplyDeleteFields(t *testing.T) {
 	assert.True(t, f.IsActive())
 
 	fields := map[string]interface{}{"value": int64(1), "value2": int64(2)}
-	assert.True(t, f.Apply("m", fields, nil))
+	assert.True(t, f.Apply("m", fields, nil, time.Now()))
 	assert.Equal(t, map[string]interface{}{"value2": int64(2)}, fields)
 }
 
@@ -56,7 +58,7 @@ func TestFilter_ApplyDeleteAllFields(t *testing.T) {
 	assert.True(t, f.IsActive())
 
 	fields := map[string]interface{}{"value": int64(1), "value2": int64(2)}
-	assert.False(t, f.Apply("m", fields, nil))
+	assert.False(t, f.Apply("m", fields, nil, time.Now()))
 }
 
 func TestFilter_Empty(t *testing.T) {
@@ -442,3 +444,45 @@ func TestFilter_FilterTagsPassAndDrop(t *testing.T) {
 	}
 
 }
+
+func TestFilter_MetricPass(t *testing.T) {
+	f := Filter{
+		MetricPass: `tags.env == "prod" && fields.value > 0`,
+		FieldDrop:  []string{"value"},
+	}
+	require.NoError(t, f.Compile())
+	assert.True(t, f.IsActive())
+
+	// the expression sees the fields before they are dropped
+	fields := map[string]interface{}{"value": int64(1), "other": int64(2)}
+	assert.True(t, f.Apply("m", fields, map[string]string{"env": "prod"},
+		time.Now()))
+	assert.Equal(t, map[string]interface{}{"other": int64(2)}, fields)
+
+	assert.False(t, f.Apply("m",
+		map[string]interface{}{"value": int64(0), "other": int64(2)},
+		map[string]string{"env": "prod"}, time.Now()))
+	assert.False(t, f.Apply("m",
+		map[string]interface{}{"value": int64(1), "other": int64(2)},
+		map[string]string{"env": "dev"}, time.Now()))
+}
+
+func TestFilter_MetricPassTime(t *testing.T) {
+	f := Filter{
+		MetricPass: `time >= "2018-01-01T00:00:00Z"`,
+	}
+	require.NoError(t, f.Compile())
+
+	fields := map[string]interface{}{"value": int64(1)}
+	assert.True(t, f.Apply("m", fields, map[string]string{},
+		time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)))
+	assert.False(t, f.Apply("m", fields, map[string]string{},
+		time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestFilter_MetricPassInvalid(t *testing.T) {
+	f := Filter{
+		MetricPass: `tags.env = "prod"`,
+	}
+	assert.Error(t, f.Compile())
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"strconv"
+	"sync"
+)
+
+// instances counts the plugins created of each kind and name, so that the
+// stats of several plugins with the same name can be told apart.
+var instances = struct {
+	sync.Mutex
+	count map[string]int
+}{count: make(map[string]int)}
+
+// instanceTags returns the tags of a stat which belongs to a single plugin:
+// its name under the kind, ie "input", and a number which sets it apart from
+// other plugins of the same name, counting from 1.
+func instanceTags(kind, name string) map[string]string {
+	instances.Lock()
+	defer instances.Unlock()
+	key := kind + "." + name
+	instances.count[key]++
+	return map[string]string{
+		kind:       name,
+		"instance": strconv.Itoa(instances.count[key]),
+	}
+}
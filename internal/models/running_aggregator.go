@@ -87,7 +87,7 @@ func (r *RunningAggregator) Add(in telegraf.Metric) bool {
 		fields := in.Fields()
 		tags := in.Tags()
 		t := in.Time()
-		if ok := r.Config.Filter.Apply(name, fields, tags); !ok {
+		if ok := r.Config.Filter.Apply(name, fields, tags, t); !ok {
 			// aggregator should not apply this metric
 			return false
 		}
@@ -2,9 +2,11 @@ package models
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/cron"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -18,6 +20,19 @@ type RunningInput struct {
 	defaultTags map[string]string
 
 	MetricsGathered selfstat.Stat
+	// ConsecutiveErrors is tagged with the instance of the input, as each
+	// input of the same name fails on its own.
+	ConsecutiveErrors selfstat.Stat
+
+	// gatherMu keeps Gather from running concurrently, for instance when a
+	// gather is requested while a scheduled one is still running.
+	gatherMu sync.Mutex
+
+	mu          sync.Mutex
+	lastErr     error
+	lastErrTime time.Time
+	// gatherFailed is set when an error is reported during a gather.
+	gatherFailed bool
 }
 
 func NewRunningInput(
@@ -32,6 +47,11 @@ func NewRunningInput(
 			"metrics_gathered",
 			map[string]string{"input": config.Name},
 		),
+		ConsecutiveErrors: selfstat.Register(
+			"gather",
+			"consecutive_errors",
+			instanceTags("input", config.Name),
+		),
 	}
 }
 
@@ -44,6 +64,19 @@ type InputConfig struct {
 	Tags              map[string]string
 	Filter            Filter
 	Interval          time.Duration
+
+	// Schedule gathers the input at the times of a cron expression instead
+	// of every Interval.
+	Schedule *cron.Schedule
+	// CollectionJitter overrides the agent's collection jitter when set.
+	CollectionJitter time.Duration
+	// CollectionOffset aligns gathers to multiples of the interval, shifted
+	// by the offset.
+	CollectionOffset time.Duration
+
+	// HealthMaxGatherErrors makes the input unhealthy once that many gathers
+	// in a row have failed. Zero disables the check.
+	HealthMaxGatherErrors int
 }
 
 func (r *RunningInput) Name() string {
@@ -83,6 +116,54 @@ func (r *RunningInput) MakeMetric(
 	return m
 }
 
+// Gather gathers the input into the accumulator. Calls are serialized, so
+// that the input is never gathered twice at the same time. A gather fails if
+// it returns an error or reports one to the accumulator, and the number of
+// failed gathers in a row is kept in ConsecutiveErrors.
+func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
+	r.gatherMu.Lock()
+	defer r.gatherMu.Unlock()
+
+	r.mu.Lock()
+	r.gatherFailed = false
+	r.mu.Unlock()
+
+	err := r.Input.Gather(acc)
+
+	r.mu.Lock()
+	failed := r.gatherFailed || err != nil
+	r.mu.Unlock()
+	if failed {
+		r.ConsecutiveErrors.Incr(1)
+	} else {
+		r.ConsecutiveErrors.Set(0)
+	}
+	return err
+}
+
+// ReleaseStats unregisters the stats which belong to this input alone, once
+// it is no longer used.
+func (r *RunningInput) ReleaseStats() {
+	selfstat.Unregister(r.ConsecutiveErrors)
+}
+
+// LogError records an error reported by the input.
+func (r *RunningInput) LogError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastErr = err
+	r.lastErrTime = time.Now()
+	r.gatherFailed = true
+}
+
+// LastError returns the last error reported by the input and when it
+// happened, or a nil error if there was none.
+func (r *RunningInput) LastError() (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastErrTime, r.lastErr
+}
+
 func (r *RunningInput) Trace() bool {
 	return r.trace
 }
@@ -456,8 +456,48 @@ func TestMakeMetric_TrailingSlash(t *testing.T) {
 	}
 }
 
+func TestRunningInputConsecutiveErrors(t *testing.T) {
+	input := &errorInput{}
+	ri := NewRunningInput(input, &InputConfig{
+		Name: "TestRunningInputErrors",
+	})
+
+	input.err = fmt.Errorf("connection refused")
+	require.Error(t, ri.Gather(nil))
+	require.Error(t, ri.Gather(nil))
+	assert.Equal(t, int64(2), ri.ConsecutiveErrors.Get())
+
+	// errors reported during the gather count as well
+	input.err = nil
+	input.logged = fmt.Errorf("partial failure")
+	input.ri = ri
+	require.NoError(t, ri.Gather(nil))
+	assert.Equal(t, int64(3), ri.ConsecutiveErrors.Get())
+	_, err := ri.LastError()
+	assert.EqualError(t, err, "partial failure")
+
+	input.logged = nil
+	require.NoError(t, ri.Gather(nil))
+	assert.Equal(t, int64(0), ri.ConsecutiveErrors.Get())
+}
+
 type testInput struct{}
 
 func (t *testInput) Description() string                   { return "" }
 func (t *testInput) SampleConfig() string                  { return "" }
 func (t *testInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+type errorInput struct {
+	err    error
+	logged error
+	ri     *RunningInput
+}
+
+func (e *errorInput) Description() string  { return "" }
+func (e *errorInput) SampleConfig() string { return "" }
+func (e *errorInput) Gather(acc telegraf.Accumulator) error {
+	if e.logged != nil {
+		e.ri.LogError(e.logged)
+	}
+	return e.err
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -32,10 +33,32 @@ type RunningOutput struct {
 	BufferSize      selfstat.Stat
 	BufferLimit     selfstat.Stat
 	WriteTime       selfstat.Stat
+	// CircuitState and WriteFailures are tagged with the instance of the
+	// output, as each output of the same name has its own circuit breaker.
+	CircuitState  selfstat.Stat
+	WriteFailures selfstat.Stat
 
 	metrics     *buffer.Buffer
 	failMetrics *buffer.Buffer
 
+	breaker *circuitBreaker
+
+	// diskBuffer replaces metrics and failMetrics when a disk buffer is
+	// configured. diskMu guards diskBuffer, so that it is only closed once
+	// nothing uses it anymore, and batchMu makes sure a batch is removed
+	// from it exactly once.
+	diskBuffer *buffer.DiskBuffer
+	diskMu     sync.RWMutex
+	batchMu    sync.Mutex
+
+	// space is signaled whenever a write makes room in the buffer, for
+	// WaitForSpace.
+	space chan struct{}
+
+	// flushMu keeps flushes from overlapping, ie a flush requested through
+	// the API with the one of the flush interval.
+	flushMu sync.Mutex
+
 	// Guards against concurrent calls to the Output as described in #3009
 	sync.Mutex
 }
@@ -86,7 +109,24 @@ func NewRunningOutput(
 			"write_time_ns",
 			map[string]string{"output": name},
 		),
+		CircuitState: selfstat.Register(
+			"write",
+			"circuit_state",
+			instanceTags("output", name),
+		),
+		WriteFailures: selfstat.Register(
+			"write",
+			"consecutive_failures",
+			instanceTags("output", name),
+		),
+		breaker: newCircuitBreaker(
+			conf.RetryBackoff,
+			conf.RetryBackoffMax,
+			conf.RetryBackoffJitter,
+		),
+		space: make(chan struct{}, 1),
 	}
+	ro.failMetrics.SetOverflowPolicy(conf.BufferOverflowPolicy)
 	ro.BufferLimit.Incr(int64(ro.MetricBufferLimit))
 	return ro
 }
@@ -105,17 +145,28 @@ func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
 		tags := m.Tags()
 		fields := m.Fields()
 		t := m.Time()
-		if ok := ro.Config.Filter.Apply(name, fields, tags); !ok {
+		if ok := ro.Config.Filter.Apply(name, fields, tags, t); !ok {
 			ro.MetricsFiltered.Incr(1)
+			m.Drop()
 			return
 		}
 		// error is not possible if creating from another metric, so ignore.
-		m, _ = metric.New(name, tags, fields, t)
+		nm, _ := metric.New(name, tags, fields, t)
+		m = metric.PassTracking(m, []telegraf.Metric{nm})[0]
+	}
+
+	if ro.addDisk(m) {
+		return
 	}
 
 	ro.metrics.Add(m)
 	if ro.metrics.Len() == ro.MetricBatchSize {
 		batch := ro.metrics.Batch(ro.MetricBatchSize)
+		// While the output is backing off, keep the batch for the next retry.
+		if !ro.breaker.Closed() {
+			ro.failMetrics.Add(batch...)
+			return
+		}
 		err := ro.write(batch)
 		if err != nil {
 			ro.failMetrics.Add(batch...)
@@ -123,8 +174,96 @@ func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
 	}
 }
 
-// Write writes all cached points to this output.
+// addDisk adds the metric to the disk buffer. It returns false if the output
+// has no disk buffer.
+func (ro *RunningOutput) addDisk(m telegraf.Metric) bool {
+	ro.diskMu.RLock()
+	defer ro.diskMu.RUnlock()
+	b := ro.diskBuffer
+	if b == nil {
+		return false
+	}
+
+	if err := b.Add(m); err != nil {
+		log.Printf("E! Output [%s] unable to add metric to disk buffer: %s",
+			ro.Name, err)
+		m.Reject()
+		return true
+	}
+	// Once on disk the metric survives a restart, which is as good as
+	// delivered for the input it came from.
+	m.Accept()
+	// Attempt a write every time another batch worth of metrics has been
+	// added, the same as the in-memory buffer does.
+	if b.Len()%ro.MetricBatchSize == 0 && ro.breaker.Closed() {
+		if _, err := ro.writeDiskBatch(b); err != nil {
+			log.Printf("E! Error writing to output [%s]: %s", ro.Name, err)
+		}
+	}
+	return true
+}
+
+// WaitForSpace blocks while the buffer of an output with the block overflow
+// policy is full, until a write makes room or shutdown is closed. It returns
+// right away for the other policies.
+func (ro *RunningOutput) WaitForSpace(shutdown chan struct{}) {
+	if ro.Config.BufferOverflowPolicy != buffer.OverflowBlock || !ro.bufferFull() {
+		return
+	}
+	log.Printf("W! Output [%s] buffer is full, waiting for a write to make room",
+		ro.Name)
+	for ro.bufferFull() {
+		select {
+		case <-ro.space:
+		case <-shutdown:
+			return
+		}
+	}
+}
+
+func (ro *RunningOutput) bufferFull() bool {
+	ro.diskMu.RLock()
+	defer ro.diskMu.RUnlock()
+	if b := ro.diskBuffer; b != nil {
+		return b.IsFull()
+	}
+	return ro.metrics.Len()+ro.failMetrics.Len() >= ro.MetricBufferLimit
+}
+
+// notifySpace wakes up a WaitForSpace waiting for room in the buffer.
+func (ro *RunningOutput) notifySpace() {
+	select {
+	case ro.space <- struct{}{}:
+	default:
+	}
+}
+
+// Write writes all cached points to this output, unless it is backing off
+// after failed writes.
 func (ro *RunningOutput) Write() error {
+	if !ro.breaker.Allow() {
+		log.Printf("D! Output [%s] is backing off, next write attempt in %s",
+			ro.Name, ro.breaker.RetryIn())
+		return nil
+	}
+	return ro.writeAll()
+}
+
+// WriteFinal writes all cached points to this output, even while it is
+// backing off. It is used for the last flush before the agent stops.
+func (ro *RunningOutput) WriteFinal() error {
+	return ro.writeAll()
+}
+
+func (ro *RunningOutput) writeAll() error {
+	ro.flushMu.Lock()
+	defer ro.flushMu.Unlock()
+	ro.CircuitState.Set(int64(ro.breaker.State()))
+
+	if ok, err := ro.writeDisk(); ok {
+		return err
+	}
+
 	nFails, nMetrics := ro.failMetrics.Len(), ro.metrics.Len()
 	ro.BufferSize.Set(int64(nFails + nMetrics))
 	log.Printf("D! Output [%s] buffer fullness: %d / %d metrics. ",
@@ -168,6 +307,136 @@ func (ro *RunningOutput) Write() error {
 	return nil
 }
 
+// writeDisk writes the metrics in the disk buffer to the output, one batch
+// at a time, stopping at the first failed write. It returns false if the
+// output has no disk buffer.
+func (ro *RunningOutput) writeDisk() (bool, error) {
+	ro.diskMu.RLock()
+	defer ro.diskMu.RUnlock()
+	b := ro.diskBuffer
+	if b == nil {
+		return false, nil
+	}
+
+	nMetrics := b.Len()
+	ro.BufferSize.Set(int64(nMetrics))
+	log.Printf("D! Output [%s] disk buffer fullness: %d metrics, %d / %d bytes. ",
+		ro.Name, nMetrics, b.Size(), b.MaxSize())
+
+	// Only write what is buffered now, so that metrics arriving during the
+	// flush can't keep it going forever.
+	nBatches := nMetrics/ro.MetricBatchSize + 1
+	for i := 0; i < nBatches; i++ {
+		n, err := ro.writeDiskBatch(b)
+		if err != nil {
+			return true, err
+		}
+		if n == 0 {
+			break
+		}
+	}
+	return true, nil
+}
+
+// writeDiskBatch writes the oldest batch in the disk buffer to the output and
+// removes it from the buffer once the write succeeded. It returns the number
+// of metrics written. The caller must hold a read lock on diskMu.
+func (ro *RunningOutput) writeDiskBatch(b *buffer.DiskBuffer) (int, error) {
+	ro.batchMu.Lock()
+	defer ro.batchMu.Unlock()
+
+	batch, err := b.Peek(ro.MetricBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if err := ro.write(batch); err != nil {
+		return 0, err
+	}
+	err = b.Remove(len(batch))
+	ro.notifySpace()
+	return len(batch), err
+}
+
+// FailingSince returns when the consecutive failed writes to the output
+// started, or the zero time if the last write succeeded.
+func (ro *RunningOutput) FailingSince() time.Time {
+	return ro.breaker.FailingSince()
+}
+
+// BufferLen returns the number of metrics waiting to be written.
+func (ro *RunningOutput) BufferLen() int {
+	ro.diskMu.RLock()
+	defer ro.diskMu.RUnlock()
+	if b := ro.diskBuffer; b != nil {
+		return b.Len()
+	}
+	return ro.metrics.Len() + ro.failMetrics.Len()
+}
+
+// BufferFill returns how full the buffer of the output is, from 0 to 1. For
+// a disk buffer it is the share of its maximum size in use.
+func (ro *RunningOutput) BufferFill() float64 {
+	ro.diskMu.RLock()
+	defer ro.diskMu.RUnlock()
+	if b := ro.diskBuffer; b != nil {
+		return float64(b.Size()) / float64(b.MaxSize())
+	}
+	nMetrics := ro.metrics.Len() + ro.failMetrics.Len()
+	fill := float64(nMetrics) / float64(ro.MetricBufferLimit)
+	if fill > 1 {
+		// the batch being filled comes on top of the limit
+		fill = 1
+	}
+	return fill
+}
+
+// OpenDiskBuffer opens the disk buffer of the output if one is configured.
+// Metrics left in it by a previous run are written on the next flush.
+func (ro *RunningOutput) OpenDiskBuffer() error {
+	ro.diskMu.Lock()
+	defer ro.diskMu.Unlock()
+	if ro.Config.DiskBufferDir == "" || ro.diskBuffer != nil {
+		return nil
+	}
+	b, err := buffer.NewDiskBuffer(
+		ro.Config.DiskBufferDir,
+		ro.Config.DiskBufferMaxSize,
+		ro.Config.DiskBufferSegmentSize,
+		ro.Config.DiskBufferFsync,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to open disk buffer for output %s: %s",
+			ro.Name, err)
+	}
+	if n := b.Len(); n > 0 {
+		log.Printf("I! Output [%s] replaying %d metrics from disk buffer %s",
+			ro.Name, n, ro.Config.DiskBufferDir)
+	}
+	b.SetOverflowPolicy(ro.Config.BufferOverflowPolicy)
+	ro.diskBuffer = b
+	return nil
+}
+
+// ReleaseStats unregisters the stats which belong to this output alone, once
+// it is no longer used.
+func (ro *RunningOutput) ReleaseStats() {
+	selfstat.Unregister(ro.CircuitState)
+	selfstat.Unregister(ro.WriteFailures)
+}
+
+// CloseDiskBuffer closes the disk buffer of the output, if it has one. It
+// waits for the writes using the disk buffer to finish.
+func (ro *RunningOutput) CloseDiskBuffer() error {
+	ro.diskMu.Lock()
+	defer ro.diskMu.Unlock()
+	if ro.diskBuffer == nil {
+		return nil
+	}
+	err := ro.diskBuffer.Close()
+	ro.diskBuffer = nil
+	return err
+}
+
 func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 	nMetrics := len(metrics)
 	if nMetrics == 0 {
@@ -183,7 +452,17 @@ func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 			ro.Name, nMetrics, elapsed)
 		ro.MetricsWritten.Incr(int64(nMetrics))
 		ro.WriteTime.Incr(elapsed.Nanoseconds())
+		ro.breaker.Success()
+		for _, m := range metrics {
+			m.Accept()
+		}
+		ro.notifySpace()
+	} else if delay := ro.breaker.Failure(); delay > 0 {
+		log.Printf("W! Output [%s] failed %d writes in a row, backing off for %s",
+			ro.Name, ro.breaker.Failures(), delay)
 	}
+	ro.CircuitState.Set(int64(ro.breaker.State()))
+	ro.WriteFailures.Set(ro.breaker.Failures())
 	return err
 }
 
@@ -191,4 +470,36 @@ func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 type OutputConfig struct {
 	Name   string
 	Filter Filter
+
+	// DiskBufferDir enables the disk buffer when set.
+	DiskBufferDir         string
+	DiskBufferMaxSize     int64
+	DiskBufferSegmentSize int64
+	DiskBufferFsync       buffer.FsyncPolicy
+
+	// RetryBackoff is the delay after a failed write before the output is
+	// written to again. It doubles with every consecutive failure up to
+	// RetryBackoffMax. Zero retries on every flush.
+	RetryBackoff       time.Duration
+	RetryBackoffMax    time.Duration
+	RetryBackoffJitter time.Duration
+
+	// FlushInterval and FlushJitter override the agent's settings when set.
+	FlushInterval time.Duration
+	FlushJitter   time.Duration
+
+	// MetricBatchSize and MetricBufferLimit override the agent's settings
+	// when set.
+	MetricBatchSize   int
+	MetricBufferLimit int
+
+	// HealthMaxFailureDuration and HealthMaxBufferFill make the output
+	// unhealthy when its writes have been failing for longer, or its buffer
+	// is fuller, in percent. Zero disables the check.
+	HealthMaxFailureDuration time.Duration
+	HealthMaxBufferFill      float64
+
+	// BufferOverflowPolicy decides what happens to metrics added to the full
+	// buffer.
+	BufferOverflowPolicy buffer.OverflowPolicy
 }
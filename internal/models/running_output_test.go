@@ -2,10 +2,15 @@ package models
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/buffer"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
@@ -479,6 +484,241 @@ func TestRunningOutputWriteFailOrder3(t *testing.T) {
 	assert.Equal(t, expected, m.Metrics())
 }
 
+func TestRunningOutputDiskBufferWriteFail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-running-output")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:        Filter{},
+		DiskBufferDir: dir,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+	require.NoError(t, ro.OpenDiskBuffer())
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	assert.Len(t, m.Metrics(), 0)
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 5)
+
+	// a successful write removes the metrics from the buffer
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 5)
+	require.NoError(t, ro.CloseDiskBuffer())
+}
+
+// Verify that metrics not yet written are replayed after a restart.
+func TestRunningOutputDiskBufferReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-running-output")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:        Filter{},
+		DiskBufferDir: dir,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+	require.NoError(t, ro.OpenDiskBuffer())
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	require.NoError(t, ro.CloseDiskBuffer())
+
+	m = &mockOutput{}
+	ro = NewRunningOutput("test", m, conf, 4, 12)
+	require.NoError(t, ro.OpenDiskBuffer())
+	defer ro.CloseDiskBuffer()
+	require.NoError(t, ro.Write())
+
+	require.Len(t, m.Metrics(), 5)
+	for i, metric := range first5 {
+		assert.Equal(t, metric.String(), m.Metrics()[i].String())
+	}
+}
+
+// Verify that a failing output is not written to again until its retry
+// backoff has passed.
+func TestRunningOutputRetryBackoff(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:       Filter{},
+		RetryBackoff: time.Hour,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	assert.Equal(t, int64(CircuitOpen), ro.CircuitState.Get())
+	assert.Equal(t, int64(1), ro.WriteFailures.Get())
+
+	// the output is skipped while backing off, and keeps its metrics
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 0)
+
+	clock := &fakeClock{t: time.Now().Add(time.Hour)}
+	ro.breaker.now = clock.now
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 5)
+	assert.Equal(t, int64(CircuitClosed), ro.CircuitState.Get())
+	assert.Equal(t, int64(0), ro.WriteFailures.Get())
+}
+
+// Verify that outputs of the same name each report the state of their own
+// circuit breaker.
+func TestRunningOutputCircuitStatePerOutput(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:       Filter{},
+		RetryBackoff: time.Hour,
+	}
+
+	failing := &mockOutput{}
+	failing.failWrite = true
+	ro1 := NewRunningOutput("test", failing, conf, 1000, 10000)
+	ro2 := NewRunningOutput("test", &mockOutput{}, conf, 1000, 10000)
+
+	for _, metric := range first5 {
+		ro1.AddMetric(metric)
+		ro2.AddMetric(metric)
+	}
+	require.Error(t, ro1.Write())
+	require.NoError(t, ro2.Write())
+	assert.Equal(t, int64(CircuitOpen), ro1.CircuitState.Get())
+	assert.Equal(t, int64(1), ro1.WriteFailures.Get())
+	assert.Equal(t, int64(CircuitClosed), ro2.CircuitState.Get())
+	assert.Equal(t, int64(0), ro2.WriteFailures.Get())
+}
+
+// Verify that the final write at shutdown doesn't wait for the backoff.
+func TestRunningOutputWriteFinal(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:       Filter{},
+		RetryBackoff: time.Hour,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+
+	m.failWrite = false
+	require.NoError(t, ro.WriteFinal())
+	assert.Len(t, m.Metrics(), 5)
+	assert.Equal(t, int64(CircuitClosed), ro.CircuitState.Get())
+}
+
+// Verify that tracked metrics are accepted once written, rejected when
+// dropped from a full buffer, and released when filtered out.
+func TestRunningOutputTracking(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			NameDrop: []string{"metric1"},
+		},
+	}
+	assert.NoError(t, conf.Filter.Compile())
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 2, 2)
+
+	delivered := map[telegraf.TrackingID]bool{}
+	onDelivery := func(info telegraf.DeliveryInfo) {
+		delivered[info.ID()] = info.Delivered()
+	}
+	var ids []telegraf.TrackingID
+	for _, m := range first5 {
+		tm, id := metric.WithTracking(m.Copy(), onDelivery)
+		ids = append(ids, id)
+		ro.AddMetric(tm)
+	}
+
+	// metric1 was filtered, metric2 and metric3 pushed out of the buffer
+	assert.Equal(t, map[telegraf.TrackingID]bool{
+		ids[0]: true,
+		ids[1]: false,
+		ids[2]: false,
+	}, delivered)
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 2)
+	assert.True(t, delivered[ids[3]])
+	assert.True(t, delivered[ids[4]])
+}
+
+// Verify that an output with the block overflow policy waits for a write to
+// make room in its full buffer, instead of dropping metrics.
+func TestRunningOutputBlockOnFullBuffer(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:               Filter{},
+		BufferOverflowPolicy: buffer.OverflowBlock,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 2, 4)
+
+	// nothing to wait for while there is room
+	ro.WaitForSpace(nil)
+	for _, metric := range first5[:4] {
+		ro.AddMetric(metric)
+	}
+	assert.Equal(t, 4, ro.BufferLen())
+
+	done := make(chan struct{})
+	go func() {
+		ro.WaitForSpace(nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("WaitForSpace returned while the buffer was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	m.Lock()
+	m.failWrite = false
+	m.Unlock()
+	require.NoError(t, ro.Write())
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("WaitForSpace did not return after a write")
+	}
+	assert.Len(t, m.Metrics(), 4)
+
+	// shutting down stops the wait as well
+	m.Lock()
+	m.failWrite = true
+	m.Unlock()
+	for _, metric := range next5[:4] {
+		ro.AddMetric(metric)
+	}
+	shutdown := make(chan struct{})
+	close(shutdown)
+	ro.WaitForSpace(shutdown)
+}
+
 type mockOutput struct {
 	sync.Mutex
 
@@ -554,3 +794,44 @@ func (m *perfOutput) Write(metrics []telegraf.Metric) error {
 	}
 	return nil
 }
+
+// Verify that closing the disk buffer while metrics are added and written
+// doesn't race with them.
+func TestRunningOutputDiskBufferCloseWhileWriting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-running-output")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:        Filter{},
+		DiskBufferDir: dir,
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 2, 12)
+	require.NoError(t, ro.OpenDiskBuffer())
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			ro.AddMetric(testutil.TestMetric(i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			ro.Write()
+			ro.BufferLen()
+		}
+	}()
+	require.NoError(t, ro.CloseDiskBuffer())
+	wg.Wait()
+
+	// every metric is either written, or left in the memory buffer or on disk
+	nMemory := ro.BufferLen()
+	require.NoError(t, ro.OpenDiskBuffer())
+	defer ro.CloseDiskBuffer()
+	assert.Equal(t, 100, len(m.Metrics())+nMemory+ro.BufferLen())
+}
@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 )
 
 type RunningProcessor struct {
@@ -33,19 +34,38 @@ func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
 
 	ret := []telegraf.Metric{}
 
-	for _, metric := range in {
+	for _, m := range in {
 		if rp.Config.Filter.IsActive() {
 			// check if the filter should be applied to this metric
-			if ok := rp.Config.Filter.Apply(metric.Name(), metric.Fields(), metric.Tags()); !ok {
+			if ok := rp.Config.Filter.Apply(m.Name(), m.Fields(), m.Tags(), m.Time()); !ok {
 				// this means filter should not be applied
-				ret = append(ret, metric)
+				ret = append(ret, m)
 				continue
 			}
 		}
 		// This metric should pass through the filter, so call the filter Apply
-		// function and append results to the output slice.
-		ret = append(ret, rp.Processor.Apply(metric)...)
+		// function and append results to the output slice. Any tracking of
+		// the metric carries over to what the processor replaced it with,
+		// unless the processor holds on to metrics and delivers them itself.
+		out := rp.Processor.Apply(m)
+		if _, ok := rp.Processor.(telegraf.BufferingProcessor); !ok {
+			out = metric.PassTracking(m, out)
+		}
+		ret = append(ret, out...)
 	}
 
 	return ret
 }
+
+// Flush returns the metrics held by a BufferingProcessor which are due to be
+// passed on, or all of them if final is set. Other processors hold nothing.
+func (rp *RunningProcessor) Flush(final bool) []telegraf.Metric {
+	p, ok := rp.Processor.(telegraf.BufferingProcessor)
+	if !ok {
+		return nil
+	}
+
+	rp.Lock()
+	defer rp.Unlock()
+	return p.Flush(final)
+}
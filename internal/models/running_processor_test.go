@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
@@ -115,3 +116,49 @@ func TestRunningProcessor_DroppedMetric(t *testing.T) {
 	}
 	assert.Equal(t, expectedNames, actualNames)
 }
+
+// HoldingProcessor holds on to all metrics until it is flushed.
+type HoldingProcessor struct {
+	held []telegraf.Metric
+}
+
+func (f *HoldingProcessor) SampleConfig() string { return "" }
+func (f *HoldingProcessor) Description() string  { return "" }
+
+func (f *HoldingProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	f.held = append(f.held, in...)
+	return nil
+}
+
+func (f *HoldingProcessor) Flush(final bool) []telegraf.Metric {
+	out := f.held
+	f.held = nil
+	return out
+}
+
+func TestRunningProcessor_HeldMetricTracking(t *testing.T) {
+	rfp := &RunningProcessor{
+		Name:      "test",
+		Processor: &HoldingProcessor{},
+		Config:    &ProcessorConfig{Filter: Filter{}},
+	}
+
+	delivered := false
+	m, _ := metric.WithTracking(testutil.TestMetric(1, "foo"),
+		func(telegraf.DeliveryInfo) { delivered = true })
+
+	assert.Empty(t, rfp.Apply(m))
+	assert.False(t, delivered)
+
+	held := rfp.Flush(true)
+	assert.Len(t, held, 1)
+	assert.False(t, delivered)
+
+	held[0].Accept()
+	assert.True(t, delivered)
+}
+
+func TestRunningProcessor_FlushNotBuffering(t *testing.T) {
+	rfp := NewTestRunningProcessor()
+	assert.Nil(t, rfp.Flush(true))
+}
@@ -61,4 +61,13 @@ type Metric interface {
 	// aggregator things:
 	SetAggregate(bool)
 	IsAggregate() bool
+
+	// delivery tracking, these do nothing unless the metric is tracked:
+	// Accept marks the metric as written by an output.
+	Accept()
+	// Reject marks the metric as discarded without being written.
+	Reject()
+	// Drop releases the metric without affecting its delivery outcome, for
+	// example when it is filtered out.
+	Drop()
 }
@@ -189,6 +189,15 @@ func (m *metric) IsAggregate() bool {
 	return m.aggregate
 }
 
+func (m *metric) Accept() {
+}
+
+func (m *metric) Reject() {
+}
+
+func (m *metric) Drop() {
+}
+
 func (m *metric) Type() telegraf.ValueType {
 	return m.mType
 }
@@ -447,64 +456,118 @@ func (m *metric) AddTag(key, value string) {
 }
 
 func (m *metric) HasTag(key string) bool {
-	i := bytes.Index(m.tags, []byte(escape(key, "tagkey")+"="))
-	if i == -1 {
-		return false
-	}
-	return true
+	i, _ := m.indexTag(key)
+	return i != -1
 }
 
 func (m *metric) RemoveTag(key string) {
 	m.hashID = 0
 
-	i := bytes.Index(m.tags, []byte(escape(key, "tagkey")+"="))
+	i, j := m.indexTag(key)
 	if i == -1 {
 		return
 	}
+	m.tags = append(m.tags[:i], m.tags[j:]...)
+}
 
-	tmp := m.tags[0 : i-1]
-	j := indexUnescapedByte(m.tags[i:], ',')
-	if j != -1 {
-		tmp = append(tmp, m.tags[i+j:]...)
+// indexTag returns the start and end index in m.tags of the tag with the
+// given key, including its leading comma, or -1 if the metric has no such
+// tag.
+func (m *metric) indexTag(key string) (int, int) {
+	k := []byte(escape(key, "tagkey"))
+	i := 0
+	for i < len(m.tags) {
+		// end index of tag key
+		i1 := indexUnescapedByte(m.tags[i:], '=')
+		if i1 == -1 {
+			break
+		}
+		// end index of tag value
+		i2 := indexUnescapedByte(m.tags[i+i1:], ',')
+		if i2 == -1 {
+			i2 = len(m.tags) - i
+		} else {
+			i2 += i1
+		}
+		if bytes.Equal(m.tags[i+1:i+i1], k) {
+			return i, i + i2
+		}
+		i += i2
 	}
-	m.tags = tmp
-	return
+	return -1, -1
 }
 
+// AddField adds a field to the metric, replacing the field with the same key
+// if there is one.
 func (m *metric) AddField(key string, value interface{}) {
+	i, j := m.indexField(key)
 	m.fields = append(m.fields, ',')
 	m.fields = appendField(m.fields, key, value)
+	if i != -1 {
+		m.removeField(i, j)
+	}
 }
 
 func (m *metric) HasField(key string) bool {
-	i := bytes.Index(m.fields, []byte(escape(key, "tagkey")+"="))
-	if i == -1 {
-		return false
-	}
-	return true
+	i, _ := m.indexField(key)
+	return i != -1
 }
 
 func (m *metric) RemoveField(key string) error {
-	i := bytes.Index(m.fields, []byte(escape(key, "tagkey")+"="))
+	i, j := m.indexField(key)
 	if i == -1 {
 		return nil
 	}
 
-	var tmp []byte
-	if i != 0 {
-		tmp = m.fields[0 : i-1]
-	}
-	j := indexUnescapedByte(m.fields[i:], ',')
-	if j != -1 {
-		tmp = append(tmp, m.fields[i+j:]...)
+	if i == 0 && j == len(m.fields) {
+		return fmt.Errorf("Metric cannot remove final field: %s", m.fields)
 	}
+	m.removeField(i, j)
+	return nil
+}
 
-	if len(tmp) == 0 {
-		return fmt.Errorf("Metric cannot remove final field: %s", m.fields)
+// removeField removes the field from index i to j from m.fields, along with
+// the comma separating it from the other fields.
+func (m *metric) removeField(i, j int) {
+	if i == 0 {
+		m.fields = append(m.fields[:0], m.fields[j+1:]...)
+	} else {
+		m.fields = append(m.fields[:i-1], m.fields[j:]...)
 	}
+}
 
-	m.fields = tmp
-	return nil
+// indexField returns the start and end index in m.fields of the field with
+// the given key, or -1 if the metric has no such field.
+func (m *metric) indexField(key string) (int, int) {
+	k := []byte(escape(key, "fieldkey"))
+	i := 0
+	for i < len(m.fields) {
+		// end index of field key
+		i1 := indexUnescapedByte(m.fields[i:], '=')
+		if i1 == -1 {
+			break
+		}
+		// end index of field value
+		var i2 int
+		if i1+1 < len(m.fields[i:]) && m.fields[i+i1+1] == '"' {
+			i2 = indexUnescapedByteBackslashEscaping(m.fields[i+i1+2:], '"')
+			if i2 == -1 {
+				i2 = len(m.fields[i:])
+			} else {
+				i2 += i1 + 3
+			}
+		} else {
+			i2 = indexUnescapedByte(m.fields[i:], ',')
+			if i2 == -1 {
+				i2 = len(m.fields[i:])
+			}
+		}
+		if bytes.Equal(m.fields[i:i+i1], k) {
+			return i, i + i2
+		}
+		i += i2 + 1
+	}
+	return -1, -1
 }
 
 func (m *metric) Copy() telegraf.Metric {
@@ -735,3 +735,49 @@ func TestNewMetric_TrailingSlash(t *testing.T) {
 		assert.Error(t, err)
 	}
 }
+
+func TestAddFieldReplaces(t *testing.T) {
+	now := time.Now()
+	m, err := New("cpu", nil, map[string]interface{}{"value": float64(1)}, now)
+	assert.NoError(t, err)
+
+	m.AddField("value", "one")
+	assert.Equal(t, map[string]interface{}{"value": "one"}, m.Fields())
+
+	m.AddField("max_value", int64(2))
+	m.AddField("value", int64(1))
+	assert.Equal(t, "cpu max_value=2i,value=1i "+fmt.Sprint(now.UnixNano())+"\n",
+		m.String())
+}
+
+func TestRemoveFieldByKey(t *testing.T) {
+	now := time.Now()
+	m, err := New("cpu", nil, map[string]interface{}{"value": float64(1)}, now)
+	assert.NoError(t, err)
+	m.AddField("status", "a,value=2")
+	m.AddField("max_value", float64(3))
+
+	assert.False(t, m.HasField("alue"))
+	assert.NoError(t, m.RemoveField("value"))
+	assert.Equal(t, map[string]interface{}{
+		"status":    "a,value=2",
+		"max_value": float64(3),
+	}, m.Fields())
+
+	assert.NoError(t, m.RemoveField("max_value"))
+	assert.Error(t, m.RemoveField("status"))
+	assert.Equal(t, `cpu status="a,value=2" `+fmt.Sprint(now.UnixNano())+"\n",
+		m.String())
+}
+
+func TestRemoveTagByKey(t *testing.T) {
+	now := time.Now()
+	m, err := New("cpu", map[string]string{"host": "a"},
+		map[string]interface{}{"value": float64(1)}, now)
+	assert.NoError(t, err)
+	m.AddTag("vhost", "b")
+
+	assert.False(t, m.HasTag("ost"))
+	m.RemoveTag("host")
+	assert.Equal(t, map[string]string{"vhost": "b"}, m.Tags())
+}
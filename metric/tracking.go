@@ -0,0 +1,148 @@
+package metric
+
+import (
+	"sync/atomic"
+
+	"github.com/influxdata/telegraf"
+)
+
+// NotifyFunc is called once the delivery outcome of a tracked metric or
+// metric group is known.
+type NotifyFunc func(telegraf.DeliveryInfo)
+
+var lastID uint64
+
+func newTrackingID() telegraf.TrackingID {
+	return telegraf.TrackingID(atomic.AddUint64(&lastID, 1))
+}
+
+// trackingData is shared by all copies of a tracked metric, and by all
+// metrics of a tracked group. Each copy holds a reference until it is
+// accepted, rejected or dropped.
+type trackingData struct {
+	id       telegraf.TrackingID
+	rc       int32
+	rejected int32
+	notify   NotifyFunc
+}
+
+func (d *trackingData) incr() {
+	atomic.AddInt32(&d.rc, 1)
+}
+
+func (d *trackingData) decr() {
+	if atomic.AddInt32(&d.rc, -1) == 0 {
+		d.notify(&deliveryInfo{
+			id:        d.id,
+			delivered: atomic.LoadInt32(&d.rejected) == 0,
+		})
+	}
+}
+
+type deliveryInfo struct {
+	id        telegraf.TrackingID
+	delivered bool
+}
+
+func (r *deliveryInfo) ID() telegraf.TrackingID {
+	return r.id
+}
+
+func (r *deliveryInfo) Delivered() bool {
+	return r.delivered
+}
+
+// trackingMetric wraps a metric and releases its reference on the tracking
+// data the first time it is accepted, rejected or dropped.
+type trackingMetric struct {
+	telegraf.Metric
+	d        *trackingData
+	released int32
+}
+
+func newTrackingMetric(m telegraf.Metric, d *trackingData) *trackingMetric {
+	d.incr()
+	return &trackingMetric{Metric: m, d: d}
+}
+
+// WithTracking returns a tracked version of the metric. fn is called once
+// the metric and all copies made of it have been released.
+func WithTracking(m telegraf.Metric, fn NotifyFunc) (telegraf.Metric, telegraf.TrackingID) {
+	d := &trackingData{id: newTrackingID(), notify: fn}
+	return newTrackingMetric(m, d), d.id
+}
+
+// WithGroupTracking returns tracked versions of the metrics, which share a
+// single delivery outcome. fn is called once all of them are released, and
+// right away if the group is empty.
+func WithGroupTracking(metrics []telegraf.Metric, fn NotifyFunc) ([]telegraf.Metric, telegraf.TrackingID) {
+	d := &trackingData{id: newTrackingID(), notify: fn}
+	if len(metrics) == 0 {
+		fn(&deliveryInfo{id: d.id, delivered: true})
+		return metrics, d.id
+	}
+
+	out := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		out = append(out, newTrackingMetric(m, d))
+	}
+	return out, d.id
+}
+
+// PassTracking hands the tracking of src over to the metrics that replace
+// it, for instance the output of a processor. If src is among them, or isn't
+// tracked, out is returned unchanged. Otherwise every metric in out becomes
+// tracked in place of src, and src is dropped.
+func PassTracking(src telegraf.Metric, out []telegraf.Metric) []telegraf.Metric {
+	tm, ok := src.(*trackingMetric)
+	if !ok {
+		return out
+	}
+	for _, m := range out {
+		if m == src {
+			return out
+		}
+	}
+
+	for i, m := range out {
+		if _, ok := m.(*trackingMetric); !ok {
+			out[i] = newTrackingMetric(m, tm.d)
+		}
+	}
+	src.Drop()
+	return out
+}
+
+// Unwrap returns the metric without its tracking, if it has any.
+func Unwrap(m telegraf.Metric) telegraf.Metric {
+	if tm, ok := m.(*trackingMetric); ok {
+		return tm.Metric
+	}
+	return m
+}
+
+func (m *trackingMetric) Copy() telegraf.Metric {
+	return newTrackingMetric(m.Metric.Copy(), m.d)
+}
+
+func (m *trackingMetric) Accept() {
+	m.release(false)
+}
+
+func (m *trackingMetric) Reject() {
+	m.release(true)
+}
+
+func (m *trackingMetric) Drop() {
+	m.release(false)
+}
+
+func (m *trackingMetric) release(rejected bool) {
+	if !atomic.CompareAndSwapInt32(&m.released, 0, 1) {
+		return
+	}
+	if rejected {
+		atomic.StoreInt32(&m.d.rejected, 1)
+	}
+	m.d.decr()
+}
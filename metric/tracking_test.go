@@ -0,0 +1,136 @@
+package metric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type deliveries struct {
+	infos []telegraf.DeliveryInfo
+}
+
+func (d *deliveries) onDelivery(info telegraf.DeliveryInfo) {
+	d.infos = append(d.infos, info)
+}
+
+func mustMetric(t *testing.T, name string) telegraf.Metric {
+	m, err := New(name,
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": float64(1)},
+		time.Unix(0, 0),
+	)
+	require.NoError(t, err)
+	return m
+}
+
+func TestTrackingAccept(t *testing.T) {
+	d := &deliveries{}
+	m, id := WithTracking(mustMetric(t, "cpu"), d.onDelivery)
+
+	m.Accept()
+	require.Len(t, d.infos, 1)
+	assert.Equal(t, id, d.infos[0].ID())
+	assert.True(t, d.infos[0].Delivered())
+
+	// releasing the same metric again has no effect
+	m.Reject()
+	assert.Len(t, d.infos, 1)
+}
+
+func TestTrackingCopies(t *testing.T) {
+	d := &deliveries{}
+	m, _ := WithTracking(mustMetric(t, "cpu"), d.onDelivery)
+	c := m.Copy()
+
+	m.Accept()
+	assert.Len(t, d.infos, 0)
+
+	c.Reject()
+	require.Len(t, d.infos, 1)
+	assert.False(t, d.infos[0].Delivered())
+}
+
+func TestTrackingDrop(t *testing.T) {
+	d := &deliveries{}
+	m, _ := WithTracking(mustMetric(t, "cpu"), d.onDelivery)
+
+	m.Drop()
+	require.Len(t, d.infos, 1)
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestGroupTracking(t *testing.T) {
+	d := &deliveries{}
+	group, id := WithGroupTracking([]telegraf.Metric{
+		mustMetric(t, "cpu"),
+		mustMetric(t, "mem"),
+	}, d.onDelivery)
+	require.Len(t, group, 2)
+
+	group[0].Accept()
+	assert.Len(t, d.infos, 0)
+	group[1].Accept()
+	require.Len(t, d.infos, 1)
+	assert.Equal(t, id, d.infos[0].ID())
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestGroupTrackingEmpty(t *testing.T) {
+	d := &deliveries{}
+	_, id := WithGroupTracking(nil, d.onDelivery)
+
+	require.Len(t, d.infos, 1)
+	assert.Equal(t, id, d.infos[0].ID())
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestPassTracking(t *testing.T) {
+	d := &deliveries{}
+	m, _ := WithTracking(mustMetric(t, "cpu"), d.onDelivery)
+
+	// the metric itself is passed on
+	out := PassTracking(m, []telegraf.Metric{m})
+	require.Len(t, out, 1)
+	assert.True(t, out[0] == m)
+
+	// replaced by new metrics, which take over the tracking
+	out = PassTracking(m, []telegraf.Metric{
+		mustMetric(t, "cpu_a"),
+		mustMetric(t, "cpu_b"),
+	})
+	require.Len(t, out, 2)
+	assert.Len(t, d.infos, 0)
+	out[0].Accept()
+	assert.Len(t, d.infos, 0)
+	out[1].Accept()
+	require.Len(t, d.infos, 1)
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestPassTrackingRemoved(t *testing.T) {
+	d := &deliveries{}
+	m, _ := WithTracking(mustMetric(t, "cpu"), d.onDelivery)
+
+	out := PassTracking(m, nil)
+	assert.Len(t, out, 0)
+	require.Len(t, d.infos, 1)
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestUnwrap(t *testing.T) {
+	d := &deliveries{}
+	inner := mustMetric(t, "cpu")
+	m, _ := WithTracking(inner, d.onDelivery)
+
+	assert.True(t, Unwrap(m) == inner)
+	assert.True(t, Unwrap(inner) == inner)
+
+	// copies of the unwrapped metric are not tracked
+	Unwrap(m).Copy().Accept()
+	assert.Len(t, d.infos, 0)
+}
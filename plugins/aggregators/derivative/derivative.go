@@ -0,0 +1,295 @@
+package derivative
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+type Derivative struct {
+	Mode       string            `toml:"mode"`
+	Suffix     string            `toml:"suffix"`
+	Fields     []string          `toml:"fields"`
+	Counter    bool              `toml:"counter"`
+	Wraparound string            `toml:"wraparound"`
+	MaxGap     internal.Duration `toml:"max_gap"`
+
+	cache       map[uint64]aggregate
+	newest      time.Time
+	fieldFilter filter.Filter
+	initialized bool
+	err         error
+}
+
+type aggregate struct {
+	name   string
+	fields map[string]*series
+	tags   map[string]string
+}
+
+// series is the last sample of a field, which is kept across periods, and
+// the sum of the deltas between its consecutive samples in this period.
+type series struct {
+	last     interface{}
+	lastTime time.Time
+
+	sum     float64
+	elapsed time.Duration
+	n       int
+}
+
+func NewDerivative() *Derivative {
+	d := &Derivative{
+		Mode:       "rate",
+		Counter:    true,
+		Wraparound: "none",
+		MaxGap:     internal.Duration{Duration: 5 * time.Minute},
+		cache:      make(map[uint64]aggregate),
+	}
+	return d
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Either "rate" to output the change per second, or "delta" to output
+  ## the change over the period.
+  # mode = "rate"
+
+  ## Suffix of the output fields, defaults to "_rate" or "_delta".
+  # suffix = "_rate"
+
+  ## Fields to compute the derivative of, supports globs. By default all
+  ## numeric fields are used.
+  # fields = ["bytes_*"]
+
+  ## If true, fields are counters which only increase and a decrease is a
+  ## reset of the counter. Set to false to compute negative changes, ie of
+  ## gauges.
+  # counter = true
+
+  ## Set to "uint32" for 32 bit counters, so that a decrease by more than
+  ## half of the range is taken as a wraparound instead of a reset. Set to
+  ## "none" if the counters never wrap.
+  # wraparound = "none"
+
+  ## Maximum time between two samples of a field. If the gap is longer,
+  ## no derivative is computed across it and a series which is not seen
+  ## for longer is forgotten. Set to "0s" for no limit.
+  # max_gap = "5m"
+`
+
+func (d *Derivative) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Derivative) Description() string {
+	return "Compute the rate or delta of the fields of each metric passing through."
+}
+
+func (d *Derivative) Add(in telegraf.Metric) {
+	if !d.initialized {
+		d.err = d.compile()
+		d.initialized = true
+	}
+	if d.err != nil {
+		return
+	}
+
+	t := in.Time()
+	if t.After(d.newest) {
+		d.newest = t
+	}
+
+	id := in.HashID()
+	a, ok := d.cache[id]
+	if !ok {
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*series),
+		}
+		d.cache[id] = a
+	}
+
+	for k, v := range in.Fields() {
+		if d.fieldFilter != nil && !d.fieldFilter.Match(k) {
+			continue
+		}
+		if _, ok := convert(v); !ok {
+			continue
+		}
+
+		s, ok := a.fields[k]
+		if !ok {
+			a.fields[k] = &series{last: v, lastTime: t}
+			continue
+		}
+
+		elapsed := t.Sub(s.lastTime)
+		if elapsed <= 0 {
+			// out of order or duplicate sample
+			continue
+		}
+		if d.MaxGap.Duration <= 0 || elapsed <= d.MaxGap.Duration {
+			if delta, ok := d.delta(s.last, v); ok {
+				s.sum += delta
+				s.elapsed += elapsed
+				s.n++
+			}
+		}
+		s.last = v
+		s.lastTime = t
+	}
+}
+
+func (d *Derivative) Push(acc telegraf.Accumulator) {
+	if d.err != nil {
+		acc.AddError(d.err)
+		return
+	}
+
+	for _, a := range d.cache {
+		fields := map[string]interface{}{}
+		for k, s := range a.fields {
+			if s.n == 0 {
+				continue
+			}
+			if d.Mode == "delta" {
+				fields[k+d.Suffix] = s.sum
+			} else {
+				fields[k+d.Suffix] = s.sum / s.elapsed.Seconds()
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields(a.name, fields, a.tags)
+		}
+	}
+}
+
+// Reset clears the deltas of the period, but keeps the last sample of each
+// series so that the next period continues from it.
+func (d *Derivative) Reset() {
+	for id, a := range d.cache {
+		for k, s := range a.fields {
+			if d.MaxGap.Duration > 0 && d.newest.Sub(s.lastTime) > d.MaxGap.Duration {
+				delete(a.fields, k)
+				continue
+			}
+			s.sum, s.elapsed, s.n = 0, 0, 0
+		}
+		if len(a.fields) == 0 {
+			delete(d.cache, id)
+		}
+	}
+}
+
+func (d *Derivative) compile() error {
+	switch d.Mode {
+	case "rate", "delta":
+	default:
+		return fmt.Errorf("derivative: invalid mode %q, must be rate or delta", d.Mode)
+	}
+	if d.Suffix == "" {
+		d.Suffix = "_" + d.Mode
+	}
+
+	switch d.Wraparound {
+	case "", "none", "uint32":
+	default:
+		return fmt.Errorf("derivative: invalid wraparound %q, must be none or uint32",
+			d.Wraparound)
+	}
+
+	f, err := filter.Compile(d.Fields)
+	if err != nil {
+		return fmt.Errorf("derivative: %s", err)
+	}
+	d.fieldFilter = f
+	return nil
+}
+
+// delta returns the change from prev to cur. If fields are counters, it
+// returns false on a reset, after unwrapping integer counters which wrapped
+// around.
+func (d *Derivative) delta(prev, cur interface{}) (float64, bool) {
+	if d.Counter {
+		p, pok := unsigned(prev)
+		c, cok := unsigned(cur)
+		if pok && cok {
+			return d.unwrap(p, c)
+		}
+	}
+
+	p, _ := convert(prev)
+	c, _ := convert(cur)
+	if d.Counter && c < p {
+		return 0, false
+	}
+	return c - p, true
+}
+
+func (d *Derivative) unwrap(prev, cur uint64) (float64, bool) {
+	if cur >= prev {
+		return float64(cur - prev), true
+	}
+
+	if d.Wraparound != "uint32" {
+		return 0, false
+	}
+	const max = math.MaxUint32
+	if prev > max {
+		return 0, false
+	}
+
+	// a counter which dropped by less than half of its range is more likely
+	// to have been reset than to have wrapped
+	wrapped := max - prev + cur + 1
+	if wrapped > max/2 {
+		return 0, false
+	}
+	return float64(wrapped), true
+}
+
+func unsigned(in interface{}) (uint64, bool) {
+	switch v := in.(type) {
+	case int64:
+		if v < 0 {
+			return 0, false
+		}
+		return uint64(v), true
+	case uint64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("derivative", func() telegraf.Aggregator {
+		return NewDerivative()
+	})
+}
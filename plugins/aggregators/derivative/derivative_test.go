@@ -0,0 +1,169 @@
+package derivative
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newMetric(seconds int, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("net", map[string]string{"interface": "eth0"}, fields,
+		start.Add(time.Duration(seconds)*time.Second))
+	return m
+}
+
+func TestRate(t *testing.T) {
+	acc := testutil.Accumulator{}
+	d := NewDerivative()
+
+	d.Add(newMetric(0, map[string]interface{}{"bytes_recv": int64(100), "up": true}))
+	d.Add(newMetric(10, map[string]interface{}{"bytes_recv": int64(300)}))
+	d.Add(newMetric(20, map[string]interface{}{"bytes_recv": int64(400)}))
+	d.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "net", map[string]interface{}{
+		"bytes_recv_rate": float64(15),
+	}, map[string]string{"interface": "eth0"})
+}
+
+func TestDeltaAcrossPeriods(t *testing.T) {
+	acc := testutil.Accumulator{}
+	d := NewDerivative()
+	d.Mode = "delta"
+
+	d.Add(newMetric(0, map[string]interface{}{"value": 1.5}))
+	d.Push(&acc)
+	assert.Empty(t, acc.Metrics)
+	d.Reset()
+
+	// the last sample of the previous period is kept
+	d.Add(newMetric(10, map[string]interface{}{"value": 4.0}))
+	d.Push(&acc)
+	d.Reset()
+
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, map[string]interface{}{"value_delta": 2.5}, acc.Metrics[0].Fields)
+}
+
+func TestCounterReset(t *testing.T) {
+	acc := testutil.Accumulator{}
+	d := NewDerivative()
+	d.Mode = "delta"
+
+	d.Add(newMetric(0, map[string]interface{}{"value": int64(100)}))
+	d.Add(newMetric(10, map[string]interface{}{"value": int64(150)}))
+	d.Add(newMetric(20, map[string]interface{}{"value": int64(5)}))
+	d.Add(newMetric(30, map[string]interface{}{"value": int64(25)}))
+	d.Push(&acc)
+
+	acc.AssertContainsFields(t, "net", map[string]interface{}{
+		"value_delta": float64(70),
+	})
+}
+
+func TestGauge(t *testing.T) {
+	acc := testutil.Accumulator{}
+	d := NewDerivative()
+	d.Counter = false
+
+	d.Add(newMetric(0, map[string]interface{}{"temp": int64(20)}))
+	d.Add(newMetric(4, map[string]interface{}{"temp": int64(18)}))
+	d.Push(&acc)
+
+	acc.AssertContainsFields(t, "net", map[string]interface{}{
+		"temp_rate": -0.5,
+	})
+}
+
+func TestWraparound(t *testing.T) {
+	tests := []struct {
+		wraparound string
+		prev, cur  interface{}
+		delta      float64
+		ok         bool
+	}{
+		{"none", int64(math.MaxUint32 - 10), int64(20), 0, false},
+		{"uint32", int64(math.MaxUint32 - 10), int64(20), 31, true},
+		{"uint32", uint64(math.MaxUint32), uint64(0), 1, true},
+		// a small decrease is a reset
+		{"uint32", int64(1000), int64(20), 0, false},
+		// the value is out of the range of a uint32
+		{"uint32", int64(math.MaxUint32 + 10), int64(20), 0, false},
+		// floats are never unwrapped
+		{"uint32", float64(math.MaxUint32 - 10), float64(20), 0, false},
+	}
+
+	for _, tt := range tests {
+		d := NewDerivative()
+		d.Wraparound = tt.wraparound
+		delta, ok := d.delta(tt.prev, tt.cur)
+		assert.Equal(t, tt.ok, ok, "%s %v %v", tt.wraparound, tt.prev, tt.cur)
+		assert.Equal(t, tt.delta, delta, "%s %v %v", tt.wraparound, tt.prev, tt.cur)
+	}
+}
+
+func TestMaxGap(t *testing.T) {
+	acc := testutil.Accumulator{}
+	d := NewDerivative()
+	d.Mode = "delta"
+	d.MaxGap.Duration = time.Minute
+
+	d.Add(newMetric(0, map[string]interface{}{"value": int64(1)}))
+	d.Add(newMetric(120, map[string]interface{}{"value": int64(10)}))
+	d.Add(newMetric(130, map[string]interface{}{"value": int64(15)}))
+	d.Push(&acc)
+
+	acc.AssertContainsFields(t, "net", map[string]interface{}{
+		"value_delta": float64(5),
+	})
+
+	// the series is forgotten once it has not been seen for max_gap
+	d.Add(newMetric(300, map[string]interface{}{"other": int64(1)}))
+	d.Reset()
+	require.Len(t, d.cache, 1)
+	for _, a := range d.cache {
+		assert.Len(t, a.fields, 1)
+		assert.Contains(t, a.fields, "other")
+	}
+}
+
+func TestFields(t *testing.T) {
+	acc := testutil.Accumulator{}
+	d := NewDerivative()
+	d.Fields = []string{"bytes_*"}
+	d.Suffix = "_per_second"
+
+	d.Add(newMetric(0, map[string]interface{}{"bytes_sent": int64(0), "packets_sent": int64(0)}))
+	d.Add(newMetric(2, map[string]interface{}{"bytes_sent": int64(10), "packets_sent": int64(2)}))
+	d.Push(&acc)
+
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, map[string]interface{}{"bytes_sent_per_second": float64(5)},
+		acc.Metrics[0].Fields)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	acc := testutil.Accumulator{}
+	d := NewDerivative()
+	d.Mode = "integral"
+
+	d.Add(newMetric(0, map[string]interface{}{"value": int64(1)}))
+	d.Add(newMetric(1, map[string]interface{}{"value": int64(2)}))
+	d.Push(&acc)
+
+	assert.Empty(t, acc.Metrics)
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), "invalid mode")
+
+	d = NewDerivative()
+	d.Wraparound = "uint64"
+	require.Error(t, d.compile())
+}
@@ -0,0 +1,107 @@
+package final
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+type Final struct {
+	SeriesTimeout internal.Duration `toml:"series_timeout"`
+	EmitTombstone bool              `toml:"emit_tombstone"`
+
+	cache map[uint64]*aggregate
+}
+
+// aggregate is the last metric of a series, which is kept across periods
+// until the series times out.
+type aggregate struct {
+	metric   telegraf.Metric
+	lastSeen time.Time
+	updated  bool
+}
+
+func NewFinal() *Final {
+	return &Final{
+		SeriesTimeout: internal.Duration{Duration: 5 * time.Minute},
+		cache:         make(map[uint64]*aggregate),
+	}
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## A series is forgotten when no metric of it has been seen for this long.
+  ## Set to "0s" to keep every series forever.
+  # series_timeout = "5m"
+
+  ## If true, a metric with the single field "expired" set to true is
+  ## emitted for a series when it times out.
+  # emit_tombstone = false
+`
+
+func (f *Final) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *Final) Description() string {
+	return "Keep the last metric of each series passing through."
+}
+
+func (f *Final) Add(in telegraf.Metric) {
+	id := in.HashID()
+	a, ok := f.cache[id]
+	if !ok {
+		a = &aggregate{}
+		f.cache[id] = a
+	}
+	if a.metric == nil || !in.Time().Before(a.metric.Time()) {
+		a.metric = in.Copy()
+	}
+	a.lastSeen = time.Now()
+	a.updated = true
+}
+
+func (f *Final) Push(acc telegraf.Accumulator) {
+	f.push(acc, time.Now())
+}
+
+func (f *Final) push(acc telegraf.Accumulator, now time.Time) {
+	for id, a := range f.cache {
+		m := a.metric
+		if a.updated {
+			acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+			continue
+		}
+
+		timeout := f.SeriesTimeout.Duration
+		if timeout > 0 && now.Sub(a.lastSeen) >= timeout {
+			delete(f.cache, id)
+			if f.EmitTombstone {
+				acc.AddFields(m.Name(), map[string]interface{}{"expired": true},
+					m.Tags(), now)
+			}
+		}
+	}
+}
+
+// Reset keeps the last metric of each series, so that it can be expired
+// once the series is quiet for longer than the timeout.
+func (f *Final) Reset() {
+	for _, a := range f.cache {
+		a.updated = false
+	}
+}
+
+func init() {
+	aggregators.Add("final", func() telegraf.Aggregator {
+		return NewFinal()
+	})
+}
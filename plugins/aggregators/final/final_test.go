@@ -0,0 +1,104 @@
+package final
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newMetric(host string, status string, seconds int) telegraf.Metric {
+	m, _ := metric.New("service",
+		map[string]string{"host": host},
+		map[string]interface{}{"status": status},
+		start.Add(time.Duration(seconds)*time.Second))
+	return m
+}
+
+func TestLastValue(t *testing.T) {
+	acc := testutil.Accumulator{}
+	f := NewFinal()
+
+	f.Add(newMetric("a", "starting", 0))
+	f.Add(newMetric("a", "running", 10))
+	f.Add(newMetric("b", "stopped", 5))
+	// late metrics do not replace newer ones
+	f.Add(newMetric("b", "running", 1))
+	f.Push(&acc)
+
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "service",
+		map[string]interface{}{"status": "running"}, map[string]string{"host": "a"})
+	acc.AssertContainsTaggedFields(t, "service",
+		map[string]interface{}{"status": "stopped"}, map[string]string{"host": "b"})
+	for _, m := range acc.Metrics {
+		if m.Tags["host"] == "a" {
+			assert.Equal(t, start.Add(10*time.Second).UnixNano(), m.Time.UnixNano())
+		}
+	}
+}
+
+func TestQuietSeries(t *testing.T) {
+	acc := testutil.Accumulator{}
+	f := NewFinal()
+
+	f.Add(newMetric("a", "running", 0))
+	f.Push(&acc)
+	f.Reset()
+
+	// the series is not emitted again until it is seen, nor is it expired
+	// before the timeout
+	acc.ClearMetrics()
+	f.push(&acc, time.Now().Add(time.Minute))
+	f.Reset()
+	assert.Empty(t, acc.Metrics)
+	assert.Len(t, f.cache, 1)
+
+	f.push(&acc, time.Now().Add(10*time.Minute))
+	assert.Empty(t, acc.Metrics)
+	assert.Empty(t, f.cache)
+}
+
+func TestTombstone(t *testing.T) {
+	acc := testutil.Accumulator{}
+	f := NewFinal()
+	f.EmitTombstone = true
+	f.SeriesTimeout.Duration = time.Minute
+
+	f.Add(newMetric("a", "running", 0))
+	f.Push(&acc)
+	f.Reset()
+
+	acc.ClearMetrics()
+	now := time.Now().Add(2 * time.Minute)
+	f.push(&acc, now)
+	f.Reset()
+
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, map[string]interface{}{"expired": true}, acc.Metrics[0].Fields)
+	assert.Equal(t, map[string]string{"host": "a"}, acc.Metrics[0].Tags)
+	assert.Equal(t, now.UnixNano(), acc.Metrics[0].Time.UnixNano())
+
+	// the tombstone is only emitted once
+	acc.ClearMetrics()
+	f.push(&acc, now.Add(time.Minute))
+	assert.Empty(t, acc.Metrics)
+}
+
+func TestNoTimeout(t *testing.T) {
+	acc := testutil.Accumulator{}
+	f := NewFinal()
+	f.SeriesTimeout.Duration = 0
+
+	f.Add(newMetric("a", "running", 0))
+	f.Reset()
+	f.push(&acc, time.Now().Add(24*time.Hour))
+	assert.Empty(t, acc.Metrics)
+	assert.Len(t, f.cache, 1)
+}
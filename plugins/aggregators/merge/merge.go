@@ -0,0 +1,88 @@
+package merge
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+type Merge struct {
+	cache map[key]aggregate
+	// order is the order in which the merged metrics were first seen, so
+	// that they are pushed in the order of their first part.
+	order []key
+}
+
+type key struct {
+	id uint64
+	t  int64
+}
+
+type aggregate struct {
+	name   string
+	fields map[string]interface{}
+	tags   map[string]string
+	t      time.Time
+}
+
+func NewMerge() telegraf.Aggregator {
+	m := &Merge{}
+	m.Reset()
+	return m
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = true
+`
+
+func (m *Merge) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *Merge) Description() string {
+	return "Merge metrics with the same name, tags and timestamp into one."
+}
+
+func (m *Merge) Add(in telegraf.Metric) {
+	k := key{id: in.HashID(), t: in.Time().UnixNano()}
+	a, ok := m.cache[k]
+	if !ok {
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]interface{}),
+			t:      in.Time(),
+		}
+		m.cache[k] = a
+		m.order = append(m.order, k)
+	}
+
+	// fields of later metrics replace those of earlier ones
+	for f, v := range in.Fields() {
+		a.fields[f] = v
+	}
+}
+
+func (m *Merge) Push(acc telegraf.Accumulator) {
+	for _, k := range m.order {
+		a := m.cache[k]
+		acc.AddFields(a.name, a.fields, a.tags, a.t)
+	}
+}
+
+func (m *Merge) Reset() {
+	m.cache = make(map[key]aggregate)
+	m.order = nil
+}
+
+func init() {
+	aggregators.Add("merge", func() telegraf.Aggregator {
+		return NewMerge()
+	})
+}
@@ -0,0 +1,76 @@
+package merge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newMetric(
+	tags map[string]string,
+	fields map[string]interface{},
+	t time.Time,
+) telegraf.Metric {
+	m, _ := metric.New("snmp", tags, fields, t)
+	return m
+}
+
+func TestMerge(t *testing.T) {
+	acc := testutil.Accumulator{}
+	m := NewMerge()
+
+	tags := map[string]string{"host": "a"}
+	m.Add(newMetric(tags, map[string]interface{}{"if_in_octets": int64(10)}, start))
+	m.Add(newMetric(tags, map[string]interface{}{"if_out_octets": int64(20)}, start))
+	m.Add(newMetric(tags, map[string]interface{}{"if_in_octets": int64(30)},
+		start.Add(time.Second)))
+	m.Add(newMetric(map[string]string{"host": "b"},
+		map[string]interface{}{"if_in_octets": int64(40)}, start))
+	m.Push(&acc)
+
+	require.Len(t, acc.Metrics, 3)
+	assert.Equal(t, map[string]interface{}{
+		"if_in_octets":  int64(10),
+		"if_out_octets": int64(20),
+	}, acc.Metrics[0].Fields)
+	assert.Equal(t, tags, acc.Metrics[0].Tags)
+	assert.Equal(t, start.UnixNano(), acc.Metrics[0].Time.UnixNano())
+
+	assert.Equal(t, map[string]interface{}{"if_in_octets": int64(30)},
+		acc.Metrics[1].Fields)
+	assert.Equal(t, start.Add(time.Second).UnixNano(), acc.Metrics[1].Time.UnixNano())
+
+	assert.Equal(t, map[string]interface{}{"if_in_octets": int64(40)},
+		acc.Metrics[2].Fields)
+	assert.Equal(t, map[string]string{"host": "b"}, acc.Metrics[2].Tags)
+}
+
+func TestMergeReplacesFields(t *testing.T) {
+	acc := testutil.Accumulator{}
+	m := NewMerge()
+
+	m.Add(newMetric(nil, map[string]interface{}{"value": 1.0, "a": 1.0}, start))
+	m.Add(newMetric(nil, map[string]interface{}{"value": 2.0}, start))
+	m.Push(&acc)
+
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, map[string]interface{}{"value": 2.0, "a": 1.0},
+		acc.Metrics[0].Fields)
+}
+
+func TestReset(t *testing.T) {
+	acc := testutil.Accumulator{}
+	m := NewMerge()
+
+	m.Add(newMetric(nil, map[string]interface{}{"value": 1.0}, start))
+	m.Reset()
+	m.Push(&acc)
+	assert.Empty(t, acc.Metrics)
+}
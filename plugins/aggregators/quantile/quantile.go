@@ -0,0 +1,161 @@
+package quantile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/tdigest"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+type Quantile struct {
+	Quantiles   []float64 `toml:"quantiles"`
+	Compression float64   `toml:"compression"`
+
+	cache    map[uint64]aggregate
+	suffixes []string
+	err      error
+}
+
+type aggregate struct {
+	name   string
+	fields map[string]*tdigest.TDigest
+	tags   map[string]string
+}
+
+func NewQuantile() *Quantile {
+	q := &Quantile{
+		Quantiles:   []float64{0.5, 0.9, 0.99, 0.999},
+		Compression: 100,
+	}
+	q.Reset()
+	return q
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Quantiles to output in the range [0,1]. A field "value" is output as
+  ## "value_p50", "value_p90", "value_p99" and "value_p999".
+  # quantiles = [0.5, 0.9, 0.99, 0.999]
+
+  ## Compression of the t-digest sketches. Higher values are more accurate,
+  ## but use more memory, see the README for the error bound.
+  # compression = 100.0
+`
+
+func (q *Quantile) SampleConfig() string {
+	return sampleConfig
+}
+
+func (q *Quantile) Description() string {
+	return "Keep the aggregate quantiles of each metric passing through."
+}
+
+func (q *Quantile) Add(in telegraf.Metric) {
+	if q.suffixes == nil && q.err == nil {
+		q.err = q.compile()
+	}
+	if q.err != nil {
+		return
+	}
+
+	id := in.HashID()
+	a, ok := q.cache[id]
+	if !ok {
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*tdigest.TDigest),
+		}
+		q.cache[id] = a
+	}
+
+	for k, v := range in.Fields() {
+		fv, ok := convert(v)
+		if !ok {
+			continue
+		}
+		td, ok := a.fields[k]
+		if !ok {
+			td = tdigest.NewWithCompression(q.Compression)
+			a.fields[k] = td
+		}
+		td.Add(fv, 1)
+	}
+}
+
+func (q *Quantile) Push(acc telegraf.Accumulator) {
+	if q.err != nil {
+		acc.AddError(q.err)
+		return
+	}
+
+	for _, a := range q.cache {
+		fields := map[string]interface{}{}
+		for k, td := range a.fields {
+			for i, quantile := range q.Quantiles {
+				fields[k+q.suffixes[i]] = td.Quantile(quantile)
+			}
+		}
+		acc.AddFields(a.name, fields, a.tags)
+	}
+}
+
+func (q *Quantile) Reset() {
+	q.cache = make(map[uint64]aggregate)
+}
+
+func (q *Quantile) compile() error {
+	if q.Compression <= 0 {
+		return fmt.Errorf("quantile: compression must be positive, got %v", q.Compression)
+	}
+
+	suffixes := make([]string, 0, len(q.Quantiles))
+	for _, quantile := range q.Quantiles {
+		if quantile < 0 || quantile > 1 {
+			return fmt.Errorf("quantile: %v is not in the range [0,1]", quantile)
+		}
+		suffixes = append(suffixes, suffix(quantile))
+	}
+	q.suffixes = suffixes
+	return nil
+}
+
+// suffix names the field of a quantile by its percentile, with at least
+// two digits before the decimals, ie "_p50" for 0.5, "_p05" for 0.05 and
+// "_p999" for 0.999.
+func suffix(quantile float64) string {
+	s := strconv.FormatFloat(quantile*100, 'f', 6, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if i := strings.IndexByte(s, '.'); i == 1 || (i == -1 && len(s) == 1) {
+		s = "0" + s
+	}
+	return "_p" + strings.Replace(s, ".", "", 1)
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("quantile", func() telegraf.Aggregator {
+		return NewQuantile()
+	})
+}
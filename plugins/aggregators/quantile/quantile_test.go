@@ -0,0 +1,117 @@
+package quantile
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("http_response", tags, fields, time.Now())
+	return m
+}
+
+func TestQuantiles(t *testing.T) {
+	acc := testutil.Accumulator{}
+	q := NewQuantile()
+	q.Quantiles = []float64{0, 0.5, 1}
+
+	for i := 1; i <= 101; i++ {
+		q.Add(newMetric(map[string]string{"server": "a"},
+			map[string]interface{}{"response_time": float64(i), "status": "ok"}))
+	}
+	q.Add(newMetric(map[string]string{"server": "b"},
+		map[string]interface{}{"response_time": int64(7)}))
+	q.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "http_response", map[string]interface{}{
+		"response_time_p00":  float64(1),
+		"response_time_p50":  float64(51),
+		"response_time_p100": float64(101),
+	}, map[string]string{"server": "a"})
+	acc.AssertContainsTaggedFields(t, "http_response", map[string]interface{}{
+		"response_time_p00":  float64(7),
+		"response_time_p50":  float64(7),
+		"response_time_p100": float64(7),
+	}, map[string]string{"server": "b"})
+}
+
+func TestReset(t *testing.T) {
+	acc := testutil.Accumulator{}
+	q := NewQuantile()
+
+	q.Add(newMetric(nil, map[string]interface{}{"value": 1.0}))
+	q.Reset()
+	q.Push(&acc)
+	assert.Empty(t, acc.Metrics)
+}
+
+// TestErrorBound checks the rank error of the estimates against the bound
+// documented in the README.
+func TestErrorBound(t *testing.T) {
+	acc := testutil.Accumulator{}
+	q := NewQuantile()
+
+	r := rand.New(rand.NewSource(42))
+	n := 100000
+	values := make([]float64, 0, n)
+	for i := 0; i < n; i++ {
+		v := r.ExpFloat64()
+		values = append(values, v)
+		q.Add(newMetric(nil, map[string]interface{}{"latency": v}))
+	}
+	sort.Float64s(values)
+	q.Push(&acc)
+
+	require.Len(t, acc.Metrics, 1)
+	fields := acc.Metrics[0].Fields
+	for i, quantile := range q.Quantiles {
+		estimate := fields["latency"+q.suffixes[i]].(float64)
+		rank := float64(sort.SearchFloat64s(values, estimate)) / float64(n)
+		bound := math.Pi*math.Sqrt(quantile*(1-quantile))/q.Compression + 1/float64(n)
+		assert.InDelta(t, quantile, rank, bound, "p%v", quantile*100)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	tests := []*Quantile{
+		{Quantiles: []float64{1.5}, Compression: 100},
+		{Quantiles: []float64{0.5}, Compression: 0},
+	}
+
+	for _, q := range tests {
+		acc := testutil.Accumulator{}
+		q.Reset()
+		q.Add(newMetric(nil, map[string]interface{}{"value": 1.0}))
+		q.Push(&acc)
+
+		assert.Empty(t, acc.Metrics)
+		assert.Len(t, acc.Errors, 1)
+	}
+}
+
+func TestSuffix(t *testing.T) {
+	tests := map[float64]string{
+		0:      "_p00",
+		0.05:   "_p05",
+		0.25:   "_p25",
+		0.5:    "_p50",
+		0.99:   "_p99",
+		0.999:  "_p999",
+		0.9999: "_p9999",
+		0.001:  "_p001",
+		1:      "_p100",
+	}
+
+	for quantile, expected := range tests {
+		assert.Equal(t, expected, suffix(quantile), "%v", quantile)
+	}
+}
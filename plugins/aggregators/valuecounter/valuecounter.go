@@ -0,0 +1,143 @@
+package valuecounter
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+// otherSuffix names the field counting the values of a field which did not
+// fit under max_values.
+const otherSuffix = "_other"
+
+type ValueCounter struct {
+	Fields    []string `toml:"fields"`
+	MaxValues int      `toml:"max_values"`
+
+	cache       map[uint64]aggregate
+	fieldFilter filter.Filter
+	initialized bool
+	err         error
+}
+
+type aggregate struct {
+	name   string
+	fields map[string]int64
+	tags   map[string]string
+	// values is the number of distinct values counted, not counting the
+	// other fields
+	values int
+}
+
+func NewValueCounter() *ValueCounter {
+	vc := &ValueCounter{
+		MaxValues: 1000,
+	}
+	vc.Reset()
+	return vc
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Fields to count the values of, supports globs. A value "200" of the
+  ## field "status_code" is counted in the field "status_code_200".
+  fields = ["status_code"]
+
+  ## Maximum number of distinct values counted for each series. Further
+  ## values are counted together in a field like "status_code_other".
+  # max_values = 1000
+`
+
+func (vc *ValueCounter) SampleConfig() string {
+	return sampleConfig
+}
+
+func (vc *ValueCounter) Description() string {
+	return "Count the occurrences of the values of fields of each metric passing through."
+}
+
+func (vc *ValueCounter) Add(in telegraf.Metric) {
+	if !vc.initialized {
+		vc.err = vc.compile()
+		vc.initialized = true
+	}
+	if vc.err != nil {
+		return
+	}
+
+	id := in.HashID()
+	a, ok := vc.cache[id]
+	if !ok {
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]int64),
+		}
+	}
+
+	for k, v := range in.Fields() {
+		if !vc.fieldFilter.Match(k) {
+			continue
+		}
+
+		key := fmt.Sprintf("%s_%v", k, v)
+		if _, ok := a.fields[key]; !ok {
+			if vc.MaxValues > 0 && a.values >= vc.MaxValues {
+				key = k + otherSuffix
+			} else {
+				a.values++
+			}
+		}
+		a.fields[key]++
+	}
+
+	if len(a.fields) > 0 {
+		vc.cache[id] = a
+	}
+}
+
+func (vc *ValueCounter) Push(acc telegraf.Accumulator) {
+	if vc.err != nil {
+		acc.AddError(vc.err)
+		return
+	}
+
+	for _, a := range vc.cache {
+		fields := make(map[string]interface{}, len(a.fields))
+		for k, count := range a.fields {
+			fields[k] = count
+		}
+		acc.AddFields(a.name, fields, a.tags)
+	}
+}
+
+func (vc *ValueCounter) Reset() {
+	vc.cache = make(map[uint64]aggregate)
+}
+
+func (vc *ValueCounter) compile() error {
+	if len(vc.Fields) == 0 {
+		return fmt.Errorf("valuecounter: no fields to count")
+	}
+
+	f, err := filter.Compile(vc.Fields)
+	if err != nil {
+		return fmt.Errorf("valuecounter: %s", err)
+	}
+	vc.fieldFilter = f
+	return nil
+}
+
+func init() {
+	aggregators.Add("valuecounter", func() telegraf.Aggregator {
+		return NewValueCounter()
+	})
+}
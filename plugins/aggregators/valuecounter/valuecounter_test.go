@@ -0,0 +1,87 @@
+package valuecounter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(server string, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("http_response", map[string]string{"server": server},
+		fields, time.Now())
+	return m
+}
+
+func TestCount(t *testing.T) {
+	acc := testutil.Accumulator{}
+	vc := NewValueCounter()
+	vc.Fields = []string{"status_code", "result_*"}
+
+	for _, code := range []int64{200, 200, 404, 200} {
+		vc.Add(newMetric("a", map[string]interface{}{
+			"status_code":   code,
+			"result_type":   "success",
+			"response_time": 0.1,
+		}))
+	}
+	vc.Add(newMetric("b", map[string]interface{}{
+		"status_code": int64(500),
+		"up":          false,
+	}))
+	vc.Add(newMetric("b", map[string]interface{}{"response_time": 0.2}))
+	vc.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "http_response", map[string]interface{}{
+		"status_code_200":     int64(3),
+		"status_code_404":     int64(1),
+		"result_type_success": int64(4),
+	}, map[string]string{"server": "a"})
+	acc.AssertContainsTaggedFields(t, "http_response", map[string]interface{}{
+		"status_code_500": int64(1),
+	}, map[string]string{"server": "b"})
+}
+
+func TestMaxValues(t *testing.T) {
+	acc := testutil.Accumulator{}
+	vc := NewValueCounter()
+	vc.Fields = []string{"status_code"}
+	vc.MaxValues = 2
+
+	for _, code := range []string{"200", "301", "404", "200", "500"} {
+		vc.Add(newMetric("a", map[string]interface{}{"status_code": code}))
+	}
+	vc.Push(&acc)
+
+	acc.AssertContainsFields(t, "http_response", map[string]interface{}{
+		"status_code_200":   int64(2),
+		"status_code_301":   int64(1),
+		"status_code_other": int64(2),
+	})
+}
+
+func TestReset(t *testing.T) {
+	acc := testutil.Accumulator{}
+	vc := NewValueCounter()
+	vc.Fields = []string{"status_code"}
+
+	vc.Add(newMetric("a", map[string]interface{}{"status_code": int64(200)}))
+	vc.Reset()
+	vc.Push(&acc)
+	assert.Empty(t, acc.Metrics)
+}
+
+func TestNoFields(t *testing.T) {
+	acc := testutil.Accumulator{}
+	vc := NewValueCounter()
+
+	vc.Add(newMetric("a", map[string]interface{}{"status_code": int64(200)}))
+	vc.Push(&acc)
+	assert.Empty(t, acc.Metrics)
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), "no fields")
+}
@@ -25,10 +25,13 @@ type AMQPConsumer struct {
 	// Binding Key
 	BindingKey string `toml:"binding_key"`
 
-	// Controls how many messages the server will try to keep on the network
-	// for consumers before receiving delivery acks.
+	// Deprecated, the prefetch count is MaxUndeliveredMessages
 	PrefetchCount int
 
+	// Maximum number of messages whose metrics are not yet written, which is
+	// also how many unacknowledged messages the server sends
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	// AMQP Auth method
 	AuthMethod string
 	// Path to CA file
@@ -55,8 +58,8 @@ func (a *externalAuth) Response() string {
 }
 
 const (
-	DefaultAuthMethod    = "PLAIN"
-	DefaultPrefetchCount = 50
+	DefaultAuthMethod             = "PLAIN"
+	DefaultMaxUndeliveredMessages = 1000
 )
 
 func (a *AMQPConsumer) SampleConfig() string {
@@ -70,8 +73,11 @@ func (a *AMQPConsumer) SampleConfig() string {
   ## Binding Key
   binding_key = "#"
 
-  ## Maximum number of messages server should give to the worker.
-  prefetch_count = 50
+  ## Maximum number of messages whose metrics have not been written by the
+  ## outputs yet. Messages are acknowledged once their metrics are written,
+  ## and rejected if they are dropped. The server sends no more than this
+  ## many unacknowledged messages, so it replaces prefetch_count.
+  # max_undelivered_messages = 1000
 
   ## Auth method. PLAIN and EXTERNAL are supported
   ## Using EXTERNAL requires enabling the rabbitmq_auth_mechanism_ssl plugin as
@@ -135,14 +141,24 @@ func (a *AMQPConsumer) Start(acc telegraf.Accumulator) error {
 		return err
 	}
 
+	if a.MaxUndeliveredMessages <= 0 {
+		a.MaxUndeliveredMessages = DefaultMaxUndeliveredMessages
+	}
+	if a.PrefetchCount != 0 {
+		log.Printf("I! WARNING amqp_consumer: prefetch_count config option is deprecated," +
+			" the prefetch count is max_undelivered_messages instead")
+	}
+
 	msgs, err := a.connect(amqpConf)
 	if err != nil {
 		return err
 	}
 
+	tacc := acc.WithTracking(a.MaxUndeliveredMessages)
+
 	a.wg = &sync.WaitGroup{}
 	a.wg.Add(1)
-	go a.process(msgs, acc)
+	go a.process(msgs, tacc)
 
 	go func() {
 		err := <-a.conn.NotifyClose(make(chan *amqp.Error))
@@ -160,7 +176,7 @@ func (a *AMQPConsumer) Start(acc telegraf.Accumulator) error {
 			}
 
 			a.wg.Add(1)
-			go a.process(msgs, acc)
+			go a.process(msgs, tacc)
 			break
 		}
 	}()
@@ -216,8 +232,10 @@ func (a *AMQPConsumer) connect(amqpConf *amqp.Config) (<-chan amqp.Delivery, err
 		return nil, fmt.Errorf("Failed to bind a queue: %s", err)
 	}
 
+	// messages are only acknowledged once their metrics are written, so the
+	// server must be able to send as many as may be waiting for that.
 	err = ch.Qos(
-		a.PrefetchCount,
+		a.MaxUndeliveredMessages,
 		0,     // prefetch-size
 		false, // global
 	)
@@ -242,22 +260,62 @@ func (a *AMQPConsumer) connect(amqpConf *amqp.Config) (<-chan amqp.Delivery, err
 	return msgs, err
 }
 
-// Read messages from queue and add them to the Accumulator
-func (a *AMQPConsumer) process(msgs <-chan amqp.Delivery, acc telegraf.Accumulator) {
+// Read messages from queue and add them to the Accumulator. A message is
+// acknowledged once its metrics are written, and no more than
+// MaxUndeliveredMessages messages are waiting for that at any time.
+func (a *AMQPConsumer) process(msgs <-chan amqp.Delivery, acc telegraf.TrackingAccumulator) {
 	defer a.wg.Done()
-	for d := range msgs {
-		metrics, err := a.parser.Parse(d.Body)
-		if err != nil {
-			log.Printf("E! %v: error parsing metric - %v", err, string(d.Body))
-		} else {
-			for _, m := range metrics {
-				acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	undelivered := make(map[telegraf.TrackingID]amqp.Delivery)
+	for {
+		in := msgs
+		if len(undelivered) >= a.MaxUndeliveredMessages {
+			in = nil
+		}
+
+		select {
+		case info := <-acc.Delivered():
+			a.onDelivery(undelivered, info)
+		case d, ok := <-in:
+			if !ok {
+				log.Printf("I! AMQP consumer queue closed")
+				return
+			}
+			metrics, err := a.parser.Parse(d.Body)
+			if err != nil {
+				log.Printf("E! %v: error parsing metric - %v", err, string(d.Body))
 			}
+			if len(metrics) == 0 {
+				d.Ack(false)
+				continue
+			}
+			id := acc.AddTrackingMetricGroup(metrics)
+			undelivered[id] = d
 		}
+	}
+}
 
-		d.Ack(false)
+// onDelivery acknowledges a message whose metrics have been written. Messages
+// whose metrics were dropped are rejected, so that the server can route them
+// to a dead letter exchange if one is configured.
+func (a *AMQPConsumer) onDelivery(undelivered map[telegraf.TrackingID]amqp.Delivery, info telegraf.DeliveryInfo) {
+	d, ok := undelivered[info.ID()]
+	if !ok {
+		return
+	}
+	delete(undelivered, info.ID())
+
+	var err error
+	if info.Delivered() {
+		err = d.Ack(false)
+	} else {
+		log.Printf("W! AMQP consumer: metrics of message %d were not delivered, rejecting it",
+			d.DeliveryTag)
+		err = d.Reject(false)
+	}
+	if err != nil {
+		log.Printf("E! AMQP consumer: unable to acknowledge message %d: %s",
+			d.DeliveryTag, err)
 	}
-	log.Printf("I! AMQP consumer queue closed")
 }
 
 func (a *AMQPConsumer) Stop() {
@@ -273,8 +331,8 @@ func (a *AMQPConsumer) Stop() {
 func init() {
 	inputs.Add("amqp_consumer", func() telegraf.Input {
 		return &AMQPConsumer{
-			AuthMethod:    DefaultAuthMethod,
-			PrefetchCount: DefaultPrefetchCount,
+			AuthMethod:             DefaultAuthMethod,
+			MaxUndeliveredMessages: DefaultMaxUndeliveredMessages,
 		}
 	})
 }
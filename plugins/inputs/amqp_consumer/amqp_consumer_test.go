@@ -0,0 +1,128 @@
+package amqp_consumer
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/agent"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/processors/topk"
+)
+
+const testMsg = "cpu_load_short,host=server01 value=23422.0 1422568543702900257\n"
+
+// acknowledger records the acknowledgements of deliveries.
+type acknowledger struct {
+	acked    chan uint64
+	rejected chan uint64
+}
+
+func newAcknowledger() *acknowledger {
+	return &acknowledger{
+		acked:    make(chan uint64, 10),
+		rejected: make(chan uint64, 10),
+	}
+}
+
+func (a *acknowledger) Ack(tag uint64, multiple bool) error {
+	a.acked <- tag
+	return nil
+}
+
+func (a *acknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.rejected <- tag
+	return nil
+}
+
+func (a *acknowledger) Reject(tag uint64, requeue bool) error {
+	a.rejected <- tag
+	return nil
+}
+
+// flakyOutput fails to write until told otherwise.
+type flakyOutput struct {
+	sync.Mutex
+	fail bool
+}
+
+func (o *flakyOutput) Connect() error       { return nil }
+func (o *flakyOutput) Close() error         { return nil }
+func (o *flakyOutput) Description() string  { return "" }
+func (o *flakyOutput) SampleConfig() string { return "" }
+
+func (o *flakyOutput) Write(metrics []telegraf.Metric) error {
+	o.Lock()
+	defer o.Unlock()
+	if o.fail {
+		return fmt.Errorf("write failed")
+	}
+	return nil
+}
+
+func (o *flakyOutput) setFail(fail bool) {
+	o.Lock()
+	defer o.Unlock()
+	o.fail = fail
+}
+
+// Test that a message is only acknowledged once an output has written its
+// metrics, also when a processor holds on to them first.
+func TestAckAfterWrite(t *testing.T) {
+	a := &AMQPConsumer{
+		MaxUndeliveredMessages: 10,
+		wg:                     &sync.WaitGroup{},
+	}
+	a.parser, _ = parsers.NewInfluxParser()
+
+	metricC := make(chan telegraf.Metric, 10)
+	input := models.NewRunningInput(a, &models.InputConfig{Name: "amqp_consumer"})
+	acc := agent.NewAccumulator(input, metricC).WithTracking(a.MaxUndeliveredMessages)
+
+	msgs := make(chan amqp.Delivery, 10)
+	a.wg.Add(1)
+	go a.process(msgs, acc)
+	defer func() {
+		close(msgs)
+		a.wg.Wait()
+	}()
+
+	processor := &models.RunningProcessor{
+		Name:      "topk",
+		Processor: topk.New(),
+		Config:    &models.ProcessorConfig{},
+	}
+	output := &flakyOutput{fail: true}
+	ro := models.NewRunningOutput("flaky", output, &models.OutputConfig{}, 0, 0)
+
+	ack := newAcknowledger()
+	msgs <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 42, Body: []byte(testMsg)}
+	assert.Empty(t, processor.Apply(<-metricC))
+	for _, m := range processor.Flush(true) {
+		ro.AddMetric(m)
+	}
+
+	require.Error(t, ro.Write())
+	select {
+	case <-ack.acked:
+		t.Fatal("message acknowledged before its metrics were written")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	output.setFail(false)
+	require.NoError(t, ro.WriteFinal())
+	select {
+	case tag := <-ack.acked:
+		assert.Equal(t, uint64(42), tag)
+	case <-time.After(time.Second):
+		t.Fatal("message not acknowledged after its metrics were written")
+	}
+	assert.Empty(t, ack.rejected)
+}
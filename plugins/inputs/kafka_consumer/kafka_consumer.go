@@ -15,6 +15,8 @@ import (
 	cluster "github.com/bsm/sarama-cluster"
 )
 
+const defaultMaxUndeliveredMessages = 1000
+
 type Kafka struct {
 	ConsumerGroup string
 	Topics        []string
@@ -45,6 +47,9 @@ type Kafka struct {
 	Offset string
 	parser parsers.Parser
 
+	// Maximum number of messages whose metrics are not yet written
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	sync.Mutex
 
 	// channel for all incoming kafka messages
@@ -54,11 +59,39 @@ type Kafka struct {
 	done chan struct{}
 
 	// keep the accumulator internally:
-	acc telegraf.Accumulator
+	acc telegraf.TrackingAccumulator
+
+	// messages waiting for their metrics to be delivered
+	undelivered map[telegraf.TrackingID]*pendingMessage
+	// messages read from each partition which are not committed yet
+	partitions map[topicPartition]*partitionOffsets
 
 	// doNotCommitMsgs tells the parser not to call CommitUpTo on the consumer
 	// this is mostly for test purposes, but there may be a use-case for it later.
 	doNotCommitMsgs bool
+	// onMarkOffset is called with each message whose offset is marked, in
+	// tests.
+	onMarkOffset func(msg *sarama.ConsumerMessage)
+}
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// partitionOffsets holds the messages read from a partition whose offsets are
+// not committed yet, in the order they were read. Once a message is not
+// delivered, the partition is blocked and no further offsets are committed,
+// so that the message is consumed again after a restart.
+type partitionOffsets struct {
+	pending []*pendingMessage
+	blocked bool
+}
+
+type pendingMessage struct {
+	msg       *sarama.ConsumerMessage
+	partition *partitionOffsets
+	delivered bool
 }
 
 var sampleConfig = `
@@ -92,6 +125,11 @@ var sampleConfig = `
   ## Maximum length of a message to consume, in bytes (default 0/unlimited);
   ## larger messages are dropped
   max_message_len = 65536
+
+  ## Maximum number of messages read from the topic(s) whose metrics have not
+  ## been written by the outputs yet. Offsets are only committed once the
+  ## metrics of a message are written, so no data is lost on restart.
+  # max_undelivered_messages = 1000
 `
 
 func (k *Kafka) SampleConfig() string {
@@ -111,7 +149,10 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 	defer k.Unlock()
 	var clusterErr error
 
-	k.acc = acc
+	if k.MaxUndeliveredMessages <= 0 {
+		k.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 
 	config := cluster.NewConfig()
 	config.Consumer.Return.Errors = true
@@ -174,9 +215,19 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 }
 
 // receiver() reads all incoming messages from the consumer, and parses them into
-// influxdb metric points.
+// influxdb metric points. Once there are MaxUndeliveredMessages messages whose
+// metrics have not been delivered yet, it waits for deliveries before reading
+// further messages.
 func (k *Kafka) receiver() {
+	k.undelivered = make(map[telegraf.TrackingID]*pendingMessage)
+	k.partitions = make(map[topicPartition]*partitionOffsets)
+
 	for {
+		in := k.in
+		if len(k.undelivered) >= k.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-k.done:
 			return
@@ -184,32 +235,107 @@ func (k *Kafka) receiver() {
 			if err != nil {
 				k.acc.AddError(fmt.Errorf("Consumer Error: %s\n", err))
 			}
-		case msg := <-k.in:
+		case info := <-k.acc.Delivered():
+			k.onDelivery(info)
+		case msg := <-in:
+			pm := k.track(msg)
 			if k.MaxMessageLen != 0 && len(msg.Value) > k.MaxMessageLen {
 				k.acc.AddError(fmt.Errorf("Message longer than max_message_len (%d > %d)",
 					len(msg.Value), k.MaxMessageLen))
-			} else {
-				metrics, err := k.parser.Parse(msg.Value)
-				if err != nil {
-					k.acc.AddError(fmt.Errorf("Message Parse Error\nmessage: %s\nerror: %s",
-						string(msg.Value), err.Error()))
-				}
-				for _, metric := range metrics {
-					k.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
-				}
+				k.settle(pm, true)
+				continue
 			}
 
-			if !k.doNotCommitMsgs {
-				// TODO(cam) this locking can be removed if this PR gets merged:
-				// https://github.com/wvanbergen/kafka/pull/84
-				k.Lock()
-				k.Cluster.MarkOffset(msg, "")
-				k.Unlock()
+			metrics, err := k.parser.Parse(msg.Value)
+			if err != nil {
+				k.acc.AddError(fmt.Errorf("Message Parse Error\nmessage: %s\nerror: %s",
+					string(msg.Value), err.Error()))
 			}
+			if len(metrics) == 0 {
+				k.settle(pm, true)
+				continue
+			}
+
+			id := k.acc.AddTrackingMetricGroup(metrics)
+			k.undelivered[id] = pm
 		}
 	}
 }
 
+func (k *Kafka) onDelivery(info telegraf.DeliveryInfo) {
+	pm, ok := k.undelivered[info.ID()]
+	if !ok {
+		return
+	}
+	delete(k.undelivered, info.ID())
+	k.settle(pm, info.Delivered())
+}
+
+// track adds a message to the pending messages of its partition. A message
+// at or before the last pending offset means that the partition is consumed
+// again from the committed offset, ie after a rebalance, so tracking starts
+// over.
+func (k *Kafka) track(msg *sarama.ConsumerMessage) *pendingMessage {
+	key := topicPartition{msg.Topic, msg.Partition}
+	p, ok := k.partitions[key]
+	if ok && len(p.pending) > 0 && msg.Offset <= p.pending[len(p.pending)-1].msg.Offset {
+		ok = false
+	}
+	if !ok {
+		p = &partitionOffsets{}
+		k.partitions[key] = p
+	}
+
+	pm := &pendingMessage{msg: msg, partition: p}
+	if !p.blocked {
+		p.pending = append(p.pending, pm)
+	}
+	return pm
+}
+
+// settle records whether the metrics of a message were written, and commits
+// the offset of its partition up to the last message which was delivered
+// along with all messages before it. Messages whose metrics were dropped are
+// never committed past, so that they are consumed again after a restart.
+func (k *Kafka) settle(pm *pendingMessage, delivered bool) {
+	p := pm.partition
+	if p.blocked {
+		return
+	}
+	if !delivered {
+		log.Printf("W! Kafka consumer: metrics of message at %s/%d/%d were not delivered, "+
+			"not committing further offsets of the partition until restart",
+			pm.msg.Topic, pm.msg.Partition, pm.msg.Offset)
+		p.blocked = true
+		p.pending = nil
+		return
+	}
+
+	pm.delivered = true
+	var last *sarama.ConsumerMessage
+	for len(p.pending) > 0 && p.pending[0].delivered {
+		last = p.pending[0].msg
+		p.pending = p.pending[1:]
+	}
+	if last != nil {
+		k.markOffset(last)
+	}
+}
+
+func (k *Kafka) markOffset(msg *sarama.ConsumerMessage) {
+	if k.onMarkOffset != nil {
+		k.onMarkOffset(msg)
+	}
+	if k.doNotCommitMsgs {
+		return
+	}
+	// TODO(cam) this locking can be removed if this PR gets merged:
+	// https://github.com/wvanbergen/kafka/pull/84
+	k.Lock()
+	k.Cluster.MarkOffset(msg, "")
+	k.Unlock()
+}
+
 func (k *Kafka) Stop() {
 	k.Lock()
 	defer k.Unlock()
@@ -225,6 +351,8 @@ func (k *Kafka) Gather(acc telegraf.Accumulator) error {
 
 func init() {
 	inputs.Add("kafka_consumer", func() telegraf.Input {
-		return &Kafka{}
+		return &Kafka{
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		}
 	})
 }
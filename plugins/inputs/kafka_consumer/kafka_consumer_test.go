@@ -1,14 +1,22 @@
 package kafka_consumer
 
 import (
+	"fmt"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/agent"
+	"github.com/influxdata/telegraf/internal/models"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/processors/topk"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/Shopify/sarama"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -29,6 +37,8 @@ func newTestKafka() (*Kafka, chan *sarama.ConsumerMessage) {
 		doNotCommitMsgs: true,
 		errs:            make(chan error, 1000),
 		done:            make(chan struct{}),
+
+		MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
 	}
 	return &k, in
 }
@@ -140,6 +150,141 @@ func TestRunParserAndGatherJSON(t *testing.T) {
 		})
 }
 
+// Test that the offset of a message is only marked once an output has
+// written its metrics, also when a processor holds on to them first.
+func TestMarkOffsetAfterWrite(t *testing.T) {
+	k, in := newTestKafka()
+	defer close(k.done)
+	k.parser, _ = parsers.NewInfluxParser()
+
+	marked := make(chan *sarama.ConsumerMessage, 1)
+	k.onMarkOffset = func(msg *sarama.ConsumerMessage) {
+		marked <- msg
+	}
+
+	metricC := make(chan telegraf.Metric, 10)
+	input := models.NewRunningInput(k, &models.InputConfig{Name: "kafka_consumer"})
+	k.acc = agent.NewAccumulator(input, metricC).WithTracking(k.MaxUndeliveredMessages)
+	go k.receiver()
+
+	processor := &models.RunningProcessor{
+		Name:      "topk",
+		Processor: topk.New(),
+		Config:    &models.ProcessorConfig{},
+	}
+	output := &flakyOutput{fail: true}
+	ro := models.NewRunningOutput("flaky", output, &models.OutputConfig{}, 0, 0)
+
+	msg := saramaMsg(testMsg)
+	in <- msg
+	assert.Empty(t, processor.Apply(<-metricC))
+	for _, m := range processor.Flush(true) {
+		ro.AddMetric(m)
+	}
+
+	require.Error(t, ro.Write())
+	select {
+	case <-marked:
+		t.Fatal("offset marked before the metrics were written")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	output.setFail(false)
+	require.NoError(t, ro.WriteFinal())
+	select {
+	case m := <-marked:
+		assert.Equal(t, msg, m)
+	case <-time.After(time.Second):
+		t.Fatal("offset not marked after the metrics were written")
+	}
+}
+
+// Test that offsets are committed in order when the metrics of messages are
+// written out of order, and never past a message whose metrics were dropped.
+func TestMarkOffsetOutOfOrder(t *testing.T) {
+	k, in := newTestKafka()
+	defer close(k.done)
+	k.parser, _ = parsers.NewInfluxParser()
+
+	marked := make(chan *sarama.ConsumerMessage, 10)
+	k.onMarkOffset = func(msg *sarama.ConsumerMessage) {
+		marked <- msg
+	}
+
+	metricC := make(chan telegraf.Metric, 10)
+	input := models.NewRunningInput(k, &models.InputConfig{Name: "kafka_consumer"})
+	k.acc = agent.NewAccumulator(input, metricC).WithTracking(k.MaxUndeliveredMessages)
+	go k.receiver()
+
+	read := func(partition int32, offset int64) telegraf.Metric {
+		msg := saramaMsg(testMsg)
+		msg.Partition = partition
+		msg.Offset = offset
+		in <- msg
+		return <-metricC
+	}
+	expectMarked := func(partition int32, offset int64) {
+		select {
+		case msg := <-marked:
+			assert.Equal(t, partition, msg.Partition)
+			assert.Equal(t, offset, msg.Offset)
+		case <-time.After(time.Second):
+			t.Fatalf("offset %d of partition %d not marked", offset, partition)
+		}
+	}
+	expectNone := func() {
+		select {
+		case msg := <-marked:
+			t.Fatalf("offset %d of partition %d marked", msg.Offset, msg.Partition)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	m0, m1, m2 := read(0, 0), read(0, 1), read(0, 2)
+	m1.Accept()
+	expectNone()
+	m0.Accept()
+	expectMarked(0, 1)
+	m2.Accept()
+	expectMarked(0, 2)
+
+	// a dropped message blocks its partition, but not the others
+	m3, m4, other := read(0, 3), read(0, 4), read(1, 0)
+	m4.Accept()
+	m3.Reject()
+	expectNone()
+	read(0, 5).Accept()
+	expectNone()
+	other.Accept()
+	expectMarked(1, 0)
+}
+
+// flakyOutput fails to write until told otherwise.
+type flakyOutput struct {
+	sync.Mutex
+	fail bool
+}
+
+func (o *flakyOutput) Connect() error       { return nil }
+func (o *flakyOutput) Close() error         { return nil }
+func (o *flakyOutput) Description() string  { return "" }
+func (o *flakyOutput) SampleConfig() string { return "" }
+
+func (o *flakyOutput) Write(metrics []telegraf.Metric) error {
+	o.Lock()
+	defer o.Unlock()
+	if o.fail {
+		return fmt.Errorf("write failed")
+	}
+	return nil
+}
+
+func (o *flakyOutput) setFail(fail bool) {
+	o.Lock()
+	defer o.Unlock()
+	o.fail = fail
+}
+
 func saramaMsg(val string) *sarama.ConsumerMessage {
 	return &sarama.ConsumerMessage{
 		Key:       nil,
@@ -18,6 +18,8 @@ import (
 // 30 Seconds is the default used by paho.mqtt.golang
 var defaultConnectionTimeout = internal.Duration{Duration: 30 * time.Second}
 
+const defaultMaxUndeliveredMessages = 1000
+
 type MQTTConsumer struct {
 	Servers           []string
 	Topics            []string
@@ -34,6 +36,9 @@ type MQTTConsumer struct {
 	PersistentSession bool
 	ClientID          string `toml:"client_id"`
 
+	// Maximum number of messages whose metrics are not yet written
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	// Path to CA file
 	SSLCA string `toml:"ssl_ca"`
 	// Path to host cert file
@@ -50,7 +55,7 @@ type MQTTConsumer struct {
 	done chan struct{}
 
 	// keep the accumulator internally:
-	acc telegraf.Accumulator
+	acc telegraf.TrackingAccumulator
 
 	connected bool
 }
@@ -79,6 +84,12 @@ var sampleConfig = `
   # If empty, a random client ID will be generated.
   client_id = ""
 
+  ## Maximum number of messages whose metrics have not been written by the
+  ## outputs yet. Once reached, no further messages are read until some of
+  ## the metrics are written. Messages are acknowledged to the broker as
+  ## they are read, not once they are written.
+  # max_undelivered_messages = 1000
+
   ## username and password to connect MQTT server.
   # username = "telegraf"
   # password = "metricsmetricsmetricsmetrics"
@@ -119,7 +130,10 @@ func (m *MQTTConsumer) Start(acc telegraf.Accumulator) error {
 			" = true, you MUST also set client_id")
 	}
 
-	m.acc = acc
+	if m.MaxUndeliveredMessages <= 0 {
+		m.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	m.acc = acc.WithTracking(m.MaxUndeliveredMessages)
 	if m.QoS > 2 || m.QoS < 0 {
 		return fmt.Errorf("MQTT Consumer, invalid QoS value: %d", m.QoS)
 	}
@@ -179,25 +193,41 @@ func (m *MQTTConsumer) onConnectionLost(c mqtt.Client, err error) {
 }
 
 // receiver() reads all incoming messages from the consumer, and parses them into
-// influxdb metric points.
+// influxdb metric points. While MaxUndeliveredMessages messages have metrics
+// that are not written yet, no further messages are read. The client library
+// acknowledges messages as they are read, so this only limits how many
+// messages are in flight and doesn't prevent losing them.
 func (m *MQTTConsumer) receiver() {
+	undelivered := 0
 	for {
+		in := m.in
+		if undelivered >= m.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-m.done:
 			return
-		case msg := <-m.in:
+		case <-m.acc.Delivered():
+			if undelivered > 0 {
+				undelivered--
+			}
+		case msg := <-in:
 			topic := msg.Topic()
 			metrics, err := m.parser.Parse(msg.Payload())
 			if err != nil {
 				m.acc.AddError(fmt.Errorf("E! MQTT Parse Error\nmessage: %s\nerror: %s",
 					string(msg.Payload()), err.Error()))
 			}
+			if len(metrics) == 0 {
+				continue
+			}
 
 			for _, metric := range metrics {
-				tags := metric.Tags()
-				tags["topic"] = topic
-				m.acc.AddFields(metric.Name(), metric.Fields(), tags, metric.Time())
+				metric.AddTag("topic", topic)
 			}
+			m.acc.AddTrackingMetricGroup(metrics)
+			undelivered++
 		}
 	}
 }
@@ -284,7 +314,8 @@ func (m *MQTTConsumer) createOpts() (*mqtt.ClientOptions, error) {
 func init() {
 	inputs.Add("mqtt_consumer", func() telegraf.Input {
 		return &MQTTConsumer{
-			ConnectionTimeout: defaultConnectionTimeout,
+			ConnectionTimeout:      defaultConnectionTimeout,
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
 		}
 	})
 }
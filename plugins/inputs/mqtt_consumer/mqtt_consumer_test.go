@@ -27,6 +27,8 @@ func newTestMQTTConsumer() (*MQTTConsumer, chan mqtt.Message) {
 		in:        in,
 		done:      make(chan struct{}),
 		connected: true,
+
+		MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
 	}
 
 	return n, in
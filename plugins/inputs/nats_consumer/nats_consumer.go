@@ -32,6 +32,9 @@ type natsConsumer struct {
 	PendingMessageLimit int
 	PendingBytesLimit   int
 
+	// Maximum number of messages whose metrics are not yet written
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	// Legacy metric buffer support
 	MetricBuffer int
 
@@ -47,9 +50,11 @@ type natsConsumer struct {
 	// channel for all NATS read errors
 	errs chan error
 	done chan struct{}
-	acc  telegraf.Accumulator
+	acc  telegraf.TrackingAccumulator
 }
 
+const defaultMaxUndeliveredMessages = 1000
+
 var sampleConfig = `
   ## urls of NATS servers
   # servers = ["nats://localhost:4222"]
@@ -65,6 +70,11 @@ var sampleConfig = `
   # pending_message_limit = 65536
   # pending_bytes_limit = 67108864
 
+  ## Maximum number of messages whose metrics have not been written by the
+  ## outputs yet. Once reached, no further messages are read until some of
+  ## the metrics are written.
+  # max_undelivered_messages = 1000
+
   ## Data format to consume.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -97,7 +107,10 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 	n.Lock()
 	defer n.Unlock()
 
-	n.acc = acc
+	if n.MaxUndeliveredMessages <= 0 {
+		n.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 
 	var connectErr error
 
@@ -154,24 +167,38 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 }
 
 // receiver() reads all incoming messages from NATS, and parses them into
-// telegraf metrics.
+// telegraf metrics. While MaxUndeliveredMessages messages have metrics that
+// are not written yet, no further messages are read, leaving them pending in
+// the subscription.
 func (n *natsConsumer) receiver() {
 	defer n.wg.Done()
+	undelivered := 0
 	for {
+		in := n.in
+		if undelivered >= n.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-n.done:
 			return
+		case <-n.acc.Delivered():
+			if undelivered > 0 {
+				undelivered--
+			}
 		case err := <-n.errs:
 			n.acc.AddError(fmt.Errorf("E! error reading from %s\n", err.Error()))
-		case msg := <-n.in:
+		case msg := <-in:
 			metrics, err := n.parser.Parse(msg.Data)
 			if err != nil {
 				n.acc.AddError(fmt.Errorf("E! subject: %s, error: %s", msg.Subject, err.Error()))
 			}
-
-			for _, metric := range metrics {
-				n.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
+			if len(metrics) == 0 {
+				continue
 			}
+
+			n.acc.AddTrackingMetricGroup(metrics)
+			undelivered++
 		}
 	}
 }
@@ -210,6 +237,8 @@ func init() {
 			QueueGroup:          "telegraf_consumers",
 			PendingBytesLimit:   nats.DefaultSubPendingBytesLimit,
 			PendingMessageLimit: nats.DefaultSubPendingMsgsLimit,
+
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
 		}
 	})
 }
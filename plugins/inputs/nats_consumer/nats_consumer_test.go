@@ -27,6 +27,8 @@ func newTestNatsConsumer() (*natsConsumer, chan *nats.Msg) {
 		in:         in,
 		errs:       make(chan error, metricBuffer),
 		done:       make(chan struct{}),
+
+		MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
 	}
 	return n, in
 }
@@ -0,0 +1,319 @@
+package converter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Tags to convert
+  ##
+  ## The table key determines the target type, and the array of key-values
+  ## select the keys to convert.  The array may contain globs.
+  ##   <target-type> = [<tag-key>...]
+  [processors.converter.tags]
+    string = []
+    integer = []
+    unsigned = []
+    boolean = []
+    float = []
+
+  ## Fields to convert
+  ##
+  ## The table key determines the target type, and the array of key-values
+  ## select the keys to convert.  The array may contain globs.
+  ##   <target-type> = [<field-key>...]
+  [processors.converter.fields]
+    tag = []
+    string = []
+    integer = []
+    unsigned = []
+    boolean = []
+    float = []
+`
+
+type Conversion struct {
+	Tag      []string `toml:"tag"`
+	String   []string `toml:"string"`
+	Integer  []string `toml:"integer"`
+	Unsigned []string `toml:"unsigned"`
+	Boolean  []string `toml:"boolean"`
+	Float    []string `toml:"float"`
+}
+
+type Converter struct {
+	Tags   *Conversion `toml:"tags"`
+	Fields *Conversion `toml:"fields"`
+
+	processors.ErrorReporter
+	tagConversions   []conversionFilter
+	fieldConversions []conversionFilter
+}
+
+type conversionType struct {
+	kind    string
+	globs   []string
+	convert func(interface{}) (interface{}, bool)
+}
+
+// conversionFilter selects the keys to convert to one type.
+type conversionFilter struct {
+	kind    string
+	filter  filter.Filter
+	convert func(interface{}) (interface{}, bool)
+}
+
+func (p *Converter) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Converter) Description() string {
+	return "Convert values to another metric value type"
+}
+
+func (p *Converter) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
+	if !p.Initialize(p.compile) {
+		return metrics
+	}
+
+	for _, metric := range metrics {
+		p.convertTags(metric)
+		p.convertFields(metric)
+	}
+	return metrics
+}
+
+func (p *Converter) compile() error {
+	var err error
+	p.tagConversions, err = compileConversion(p.Tags, false)
+	if err != nil {
+		return fmt.Errorf("converter tags: %s", err)
+	}
+	p.fieldConversions, err = compileConversion(p.Fields, true)
+	if err != nil {
+		return fmt.Errorf("converter fields: %s", err)
+	}
+	return nil
+}
+
+// compileConversion compiles the globs of each type, in the order in which
+// they take precedence if a key matches more than one.
+func compileConversion(c *Conversion, fields bool) ([]conversionFilter, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	var types []conversionType
+	if fields {
+		types = append(types, conversionType{"tag", c.Tag, toString})
+	}
+	types = append(types,
+		conversionType{"string", c.String, toString},
+		conversionType{"integer", c.Integer, toInteger},
+		conversionType{"unsigned", c.Unsigned, toUnsigned},
+		conversionType{"boolean", c.Boolean, toBool},
+		conversionType{"float", c.Float, toFloat},
+	)
+
+	var conversions []conversionFilter
+	for _, t := range types {
+		f, err := filter.Compile(t.globs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s keys: %s", t.kind, err)
+		}
+		if f != nil {
+			conversions = append(conversions, conversionFilter{t.kind, f, t.convert})
+		}
+	}
+	return conversions, nil
+}
+
+// match returns the conversion for the key, or nil if it isn't converted.
+func match(conversions []conversionFilter, key string) *conversionFilter {
+	for i := range conversions {
+		if conversions[i].filter.Match(key) {
+			return &conversions[i]
+		}
+	}
+	return nil
+}
+
+// convertTags turns the selected tags into fields of their type.
+func (p *Converter) convertTags(metric telegraf.Metric) {
+	for key, value := range metric.Tags() {
+		c := match(p.tagConversions, key)
+		if c == nil {
+			continue
+		}
+
+		metric.RemoveTag(key)
+		v, ok := c.convert(value)
+		if !ok {
+			p.AddError(fmt.Errorf("converter: cannot convert tag %q of %s with value %q to %s",
+				key, metric.Name(), value, c.kind))
+			continue
+		}
+		metric.AddField(key, v)
+	}
+}
+
+// convertFields changes the type of the selected fields, or turns them into
+// tags. Fields that can't be converted are removed, unless they are the last
+// field of the metric.
+func (p *Converter) convertFields(metric telegraf.Metric) {
+	for key, value := range metric.Fields() {
+		c := match(p.fieldConversions, key)
+		if c == nil {
+			continue
+		}
+
+		v, ok := c.convert(value)
+		if !ok {
+			p.AddError(fmt.Errorf("converter: cannot convert field %q of %s with value %v to %s",
+				key, metric.Name(), value, c.kind))
+			metric.RemoveField(key)
+			continue
+		}
+
+		if c.kind == "tag" {
+			if err := metric.RemoveField(key); err != nil {
+				p.AddError(fmt.Errorf("converter: cannot convert field %q of %s to a tag: %s",
+					key, metric.Name(), err))
+				continue
+			}
+			metric.AddTag(key, v.(string))
+			continue
+		}
+		metric.AddField(key, v)
+	}
+}
+
+func toBool(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case int64:
+		return value != 0, true
+	case uint64:
+		return value != 0, true
+	case float64:
+		return value != 0, true
+	case bool:
+		return value, true
+	case string:
+		result, err := strconv.ParseBool(value)
+		return result, err == nil
+	}
+	return nil, false
+}
+
+func toInteger(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case int64:
+		return value, true
+	case uint64:
+		if value > math.MaxInt64 {
+			return nil, false
+		}
+		return int64(value), true
+	case float64:
+		if math.IsNaN(value) || value < math.MinInt64 || value >= math.MaxInt64 {
+			return nil, false
+		}
+		return int64(value), true
+	case bool:
+		if value {
+			return int64(1), true
+		}
+		return int64(0), true
+	case string:
+		result, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, false
+			}
+			return toInteger(f)
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+func toUnsigned(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case int64:
+		if value < 0 {
+			return nil, false
+		}
+		return uint64(value), true
+	case uint64:
+		return value, true
+	case float64:
+		if math.IsNaN(value) || value < 0 || value >= math.MaxUint64 {
+			return nil, false
+		}
+		return uint64(value), true
+	case bool:
+		if value {
+			return uint64(1), true
+		}
+		return uint64(0), true
+	case string:
+		result, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, false
+			}
+			return toUnsigned(f)
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+func toFloat(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case int64:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case float64:
+		return value, true
+	case bool:
+		if value {
+			return float64(1), true
+		}
+		return float64(0), true
+	case string:
+		result, err := strconv.ParseFloat(value, 64)
+		return result, err == nil
+	}
+	return nil, false
+}
+
+func toString(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case int64:
+		return strconv.FormatInt(value, 10), true
+	case uint64:
+		return strconv.FormatUint(value, 10), true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(value), true
+	case string:
+		return value, true
+	}
+	return nil, false
+}
+
+func init() {
+	processors.Add("converter", func() telegraf.Processor {
+		return &Converter{}
+	})
+}
@@ -0,0 +1,235 @@
+package converter
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Metric(v telegraf.Metric, err error) telegraf.Metric {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestConverter(t *testing.T) {
+	tests := []struct {
+		name      string
+		converter *Converter
+		input     telegraf.Metric
+		tags      map[string]string
+		fields    map[string]interface{}
+		errors    int
+	}{
+		{
+			name:      "empty",
+			converter: &Converter{},
+			input: Metric(metric.New("cpu",
+				map[string]string{"host": "localhost"},
+				map[string]interface{}{"value": 42.0},
+				time.Unix(0, 0))),
+			tags:   map[string]string{"host": "localhost"},
+			fields: map[string]interface{}{"value": 42.0},
+		},
+		{
+			name: "from tag",
+			converter: &Converter{
+				Tags: &Conversion{
+					String:   []string{"string"},
+					Integer:  []string{"int"},
+					Unsigned: []string{"uint"},
+					Boolean:  []string{"bool"},
+					Float:    []string{"float"},
+				},
+			},
+			input: Metric(metric.New("cpu",
+				map[string]string{
+					"string": "howdy",
+					"int":    "-42",
+					"uint":   "42",
+					"bool":   "true",
+					"float":  "4.2",
+					"host":   "localhost",
+				},
+				map[string]interface{}{"value": 42.0},
+				time.Unix(0, 0))),
+			tags: map[string]string{"host": "localhost"},
+			fields: map[string]interface{}{
+				"value":  42.0,
+				"string": "howdy",
+				"int":    int64(-42),
+				"uint":   int64(42),
+				"bool":   true,
+				"float":  4.2,
+			},
+		},
+		{
+			name: "from tag unconvertible",
+			converter: &Converter{
+				Tags: &Conversion{
+					Integer: []string{"int"},
+					Boolean: []string{"bool"},
+				},
+			},
+			input: Metric(metric.New("cpu",
+				map[string]string{"int": "a", "bool": "maybe"},
+				map[string]interface{}{"value": 42.0},
+				time.Unix(0, 0))),
+			tags:   map[string]string{},
+			fields: map[string]interface{}{"value": 42.0},
+			errors: 2,
+		},
+		{
+			name: "from string field",
+			converter: &Converter{
+				Fields: &Conversion{
+					Tag:      []string{"tag"},
+					Integer:  []string{"a", "b"},
+					Unsigned: []string{"c", "d"},
+					Boolean:  []string{"e", "f"},
+					Float:    []string{"g"},
+				},
+			},
+			input: Metric(metric.New("cpu",
+				map[string]string{},
+				map[string]interface{}{
+					"tag": "localhost",
+					"a":   "42",
+					"b":   "42.5",
+					"c":   "42",
+					"d":   "-42",
+					"e":   "0",
+					"f":   "nope",
+					"g":   "4.2e1",
+				},
+				time.Unix(0, 0))),
+			tags: map[string]string{"tag": "localhost"},
+			fields: map[string]interface{}{
+				"a": int64(42),
+				"b": int64(42),
+				"c": int64(42),
+				"e": false,
+				"g": 42.0,
+			},
+			errors: 2,
+		},
+		{
+			name: "from numeric field",
+			converter: &Converter{
+				Fields: &Conversion{
+					String:   []string{"a"},
+					Integer:  []string{"b", "c"},
+					Unsigned: []string{"d"},
+					Boolean:  []string{"e"},
+					Float:    []string{"f"},
+				},
+			},
+			input: Metric(metric.New("cpu",
+				map[string]string{},
+				map[string]interface{}{
+					"a": 42.5,
+					"b": true,
+					"c": 1e30,
+					"d": 42.9,
+					"e": int64(42),
+					"f": int64(42),
+				},
+				time.Unix(0, 0))),
+			tags: map[string]string{},
+			fields: map[string]interface{}{
+				"a": "42.5",
+				"b": int64(1),
+				"d": int64(42),
+				"e": true,
+				"f": 42.0,
+			},
+			errors: 1,
+		},
+		{
+			name: "globs and precedence",
+			converter: &Converter{
+				Fields: &Conversion{
+					Tag:     []string{"host"},
+					Integer: []string{"*"},
+				},
+			},
+			input: Metric(metric.New("cpu",
+				map[string]string{},
+				map[string]interface{}{
+					"host":  "localhost",
+					"value": 42.0,
+					"count": "42",
+				},
+				time.Unix(0, 0))),
+			tags: map[string]string{"host": "localhost"},
+			fields: map[string]interface{}{
+				"value": int64(42),
+				"count": int64(42),
+			},
+		},
+		{
+			name: "last field to tag",
+			converter: &Converter{
+				Fields: &Conversion{
+					Tag: []string{"host"},
+				},
+			},
+			input: Metric(metric.New("cpu",
+				map[string]string{},
+				map[string]interface{}{"host": "localhost"},
+				time.Unix(0, 0))),
+			tags:   map[string]string{},
+			fields: map[string]interface{}{"host": "localhost"},
+			errors: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var acc testutil.Accumulator
+			tt.converter.SetAccumulator(&acc)
+
+			metrics := tt.converter.Apply(tt.input)
+
+			require.Len(t, metrics, 1)
+			assert.Equal(t, "cpu", metrics[0].Name())
+			assert.Equal(t, tt.tags, metrics[0].Tags())
+			assert.Equal(t, tt.fields, metrics[0].Fields())
+			assert.Len(t, acc.Errors, tt.errors)
+		})
+	}
+}
+
+func TestConverterInvalidGlob(t *testing.T) {
+	var acc testutil.Accumulator
+	converter := &Converter{
+		Fields: &Conversion{
+			Integer: []string{"[a"},
+		},
+	}
+	converter.SetAccumulator(&acc)
+
+	m := Metric(metric.New("cpu", nil,
+		map[string]interface{}{"a": "42"}, time.Unix(0, 0)))
+	metrics := converter.Apply(m)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{"a": "42"}, metrics[0].Fields())
+
+	converter.Apply(m)
+	assert.Len(t, acc.Errors, 1)
+}
+
+func TestToUnsigned(t *testing.T) {
+	_, ok := toUnsigned(math.NaN())
+	assert.False(t, ok)
+	v, ok := toUnsigned(uint64(math.MaxUint64))
+	assert.True(t, ok)
+	assert.Equal(t, uint64(math.MaxUint64), v)
+}
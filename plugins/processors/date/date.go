@@ -0,0 +1,141 @@
+package date
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## New tag to create
+  tag_key = "month"
+
+  ## New field to create (cannot set both field_key and tag_key)
+  # field_key = "month"
+
+  ## Date format string, must be a representation of the Go "reference time"
+  ## which is "Mon Jan 2 15:04:05 -0700 MST 2006". The formats "unix",
+  ## "unix_ms", "unix_us" and "unix_ns" give the time since the epoch, as an
+  ## integer if written to a field.
+  date_format = "Jan"
+
+  ## Offset duration added to the time of the metric to create the tag or
+  ## field, ie "1h" to tag with the next hour.
+  # date_offset = "0s"
+
+  ## Timezone to use when creating the tag or field using a reference time
+  ## string. This can be set to one of "UTC", "Local", or to a location name
+  ## in the IANA Time Zone database.
+  # timezone = "UTC"
+
+  ## Duration added to the timestamp of the metric itself, ie to correct a
+  ## source with a known clock skew. The tag or field is created from the
+  ## shifted time.
+  # shift_timestamp = "0s"
+`
+
+type Date struct {
+	TagKey         string            `toml:"tag_key"`
+	FieldKey       string            `toml:"field_key"`
+	DateFormat     string            `toml:"date_format"`
+	DateOffset     internal.Duration `toml:"date_offset"`
+	Timezone       string            `toml:"timezone"`
+	ShiftTimestamp internal.Duration `toml:"shift_timestamp"`
+
+	processors.ErrorReporter
+	location *time.Location
+}
+
+func New() *Date {
+	return &Date{
+		Timezone: "UTC",
+	}
+}
+
+func (d *Date) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Date) Description() string {
+	return "Add a tag or field derived from the time of the metric"
+}
+
+func (d *Date) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !d.Initialize(d.compile) {
+		return in
+	}
+
+	for i, m := range in {
+		if d.ShiftTimestamp.Duration != 0 {
+			shifted, err := shift(m, d.ShiftTimestamp.Duration)
+			if err != nil {
+				d.AddError(fmt.Errorf("date: cannot shift the timestamp of %s: %s",
+					m.Name(), err))
+			} else {
+				in[i], m = shifted, shifted
+			}
+		}
+
+		t := m.Time().Add(d.DateOffset.Duration).In(d.location)
+		if d.TagKey != "" {
+			m.AddTag(d.TagKey, fmt.Sprint(format(t, d.DateFormat)))
+		}
+		if d.FieldKey != "" {
+			m.AddField(d.FieldKey, format(t, d.DateFormat))
+		}
+	}
+	return in
+}
+
+func (d *Date) compile() error {
+	if d.TagKey != "" && d.FieldKey != "" {
+		return fmt.Errorf("date: only one of tag_key and field_key can be set")
+	}
+	if (d.TagKey != "" || d.FieldKey != "") && d.DateFormat == "" {
+		return fmt.Errorf("date: date_format must be set")
+	}
+
+	var err error
+	d.location, err = time.LoadLocation(d.Timezone)
+	if err != nil {
+		return fmt.Errorf("date: invalid timezone %q: %s", d.Timezone, err)
+	}
+	return nil
+}
+
+// format formats t by the reference time layout, or returns the time since
+// the epoch for the unix formats.
+func format(t time.Time, layout string) interface{} {
+	switch layout {
+	case "unix":
+		return t.Unix()
+	case "unix_ms":
+		return t.UnixNano() / int64(time.Millisecond)
+	case "unix_us":
+		return t.UnixNano() / int64(time.Microsecond)
+	case "unix_ns":
+		return t.UnixNano()
+	}
+	return t.Format(layout)
+}
+
+// shift returns a copy of the metric with its timestamp moved by d, as the
+// timestamp of a metric can't be changed.
+func shift(m telegraf.Metric, d time.Duration) (telegraf.Metric, error) {
+	shifted, err := metric.New(m.Name(), m.Tags(), m.Fields(), m.Time().Add(d), m.Type())
+	if err != nil {
+		return nil, err
+	}
+	shifted.SetAggregate(m.IsAggregate())
+	return shifted, nil
+}
+
+func init() {
+	processors.Add("date", func() telegraf.Processor {
+		return New()
+	})
+}
@@ -0,0 +1,116 @@
+package date
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func MustMetric(name string, tags map[string]string, fields map[string]interface{}, metricTime time.Time) telegraf.Metric {
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	if fields == nil {
+		fields = map[string]interface{}{}
+	}
+	m, _ := metric.New(name, tags, fields, metricTime)
+	return m
+}
+
+func TestMonthTag(t *testing.T) {
+	dateFormatMonth := New()
+	dateFormatMonth.TagKey = "month"
+	dateFormatMonth.DateFormat = "Jan"
+
+	currentTime := time.Now()
+	month := currentTime.UTC().Format("Jan")
+
+	m1 := MustMetric("foo", nil, map[string]interface{}{"value": 1.0}, currentTime)
+	m2 := MustMetric("bar", nil, map[string]interface{}{"value": 1.0}, currentTime)
+	m3 := MustMetric("baz", nil, map[string]interface{}{"value": 1.0}, currentTime)
+	monthApply := dateFormatMonth.Apply(m1, m2, m3)
+	assert.Equal(t, map[string]string{"month": month}, monthApply[0].Tags(), "should add tag 'month'")
+	assert.Equal(t, map[string]string{"month": month}, monthApply[1].Tags(), "should add tag 'month'")
+	assert.Equal(t, map[string]string{"month": month}, monthApply[2].Tags(), "should add tag 'month'")
+}
+
+func TestFieldFormats(t *testing.T) {
+	tm := time.Date(2018, 3, 4, 22, 30, 15, 0, time.UTC)
+	tests := []struct {
+		format   string
+		timezone string
+		expected interface{}
+	}{
+		{"2006-01-02", "UTC", "2018-03-04"},
+		{"Monday", "UTC", "Sunday"},
+		{"15", "UTC", "22"},
+		{"15", "Asia/Tokyo", "07"},
+		{"Monday", "Asia/Tokyo", "Monday"},
+		{"unix", "UTC", int64(1520202615)},
+		{"unix_ms", "UTC", int64(1520202615000)},
+		{"unix_ns", "Local", int64(1520202615000000000)},
+	}
+
+	for _, tt := range tests {
+		d := New()
+		d.FieldKey = "date"
+		d.DateFormat = tt.format
+		d.Timezone = tt.timezone
+
+		out := d.Apply(MustMetric("foo", nil, map[string]interface{}{"value": 1.0}, tm))
+		assert.Equal(t, tt.expected, out[0].Fields()["date"], tt.format+" "+tt.timezone)
+	}
+}
+
+func TestDateOffset(t *testing.T) {
+	d := New()
+	d.TagKey = "hour"
+	d.DateFormat = "15"
+	d.DateOffset.Duration = 2 * time.Hour
+
+	tm := time.Date(2018, 3, 4, 22, 30, 15, 0, time.UTC)
+	out := d.Apply(MustMetric("foo", nil, map[string]interface{}{"value": 1.0}, tm))
+	assert.Equal(t, "00", out[0].Tags()["hour"])
+	assert.Equal(t, tm.UnixNano(), out[0].UnixNano())
+}
+
+func TestShiftTimestamp(t *testing.T) {
+	d := New()
+	d.TagKey = "hour"
+	d.DateFormat = "15"
+	d.ShiftTimestamp.Duration = -time.Hour
+
+	tm := time.Date(2018, 3, 4, 22, 30, 15, 0, time.UTC)
+	in := MustMetric("foo", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, tm)
+	out := d.Apply(in)
+
+	require.Len(t, out, 1)
+	assert.Equal(t, tm.Add(-time.Hour).UnixNano(), out[0].UnixNano())
+	assert.Equal(t, "foo", out[0].Name())
+	assert.Equal(t, map[string]string{"host": "a", "hour": "21"}, out[0].Tags())
+	assert.Equal(t, map[string]interface{}{"value": 1.0}, out[0].Fields())
+}
+
+func TestInvalidConfig(t *testing.T) {
+	tests := []*Date{
+		{TagKey: "a", FieldKey: "b", DateFormat: "Jan"},
+		{TagKey: "a"},
+		{TagKey: "a", DateFormat: "Jan", Timezone: "Mars/Olympus_Mons"},
+	}
+
+	for _, d := range tests {
+		var acc testutil.Accumulator
+		d.SetAccumulator(&acc)
+
+		m := MustMetric("foo", nil, map[string]interface{}{"value": 1.0}, time.Now())
+		d.Apply(m)
+		out := d.Apply(m)
+		assert.Empty(t, out[0].Tags())
+		assert.Len(t, acc.Errors, 1)
+	}
+}
@@ -0,0 +1,131 @@
+package dedup
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Maximum time to suppress output of a series whose fields don't change
+  dedup_interval = "600s"
+
+  ## Maximum number of series to remember. The series that were passed on
+  ## the longest time ago are forgotten first.
+  max_series = 10000
+`
+
+type Dedup struct {
+	DedupInterval internal.Duration `toml:"dedup_interval"`
+	MaxSeries     int               `toml:"max_series"`
+
+	// series keeps the entries ordered by when their metric was passed on,
+	// the oldest first, and cache indexes them by series.
+	series *list.List
+	cache  map[uint64]*list.Element
+}
+
+// entry is the last metric passed on for a series.
+type entry struct {
+	id      uint64
+	fields  map[string]interface{}
+	time    time.Time
+	expires time.Time
+}
+
+func New() *Dedup {
+	return &Dedup{
+		DedupInterval: internal.Duration{Duration: 10 * time.Minute},
+		MaxSeries:     10000,
+	}
+}
+
+func (d *Dedup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Dedup) Description() string {
+	return "Drop metrics whose fields didn't change since they were last passed on"
+}
+
+func (d *Dedup) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if d.cache == nil {
+		d.series = list.New()
+		d.cache = make(map[uint64]*list.Element)
+	}
+	d.evictStale(time.Now())
+
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, metric := range in {
+		id := metric.HashID()
+		fields := metric.Fields()
+
+		if el, ok := d.cache[id]; ok {
+			e := el.Value.(*entry)
+			if equal(e.fields, fields) && metric.Time().Sub(e.time) < d.DedupInterval.Duration {
+				// an unchanged value within the interval is dropped
+				continue
+			}
+			d.series.Remove(el)
+			delete(d.cache, id)
+		}
+
+		d.remember(id, fields, metric.Time())
+		out = append(out, metric)
+	}
+	return out
+}
+
+// remember records the metric passed on for a series, forgetting the oldest
+// series if there are too many.
+func (d *Dedup) remember(id uint64, fields map[string]interface{}, t time.Time) {
+	if d.MaxSeries > 0 {
+		for d.series.Len() >= d.MaxSeries {
+			oldest := d.series.Front()
+			delete(d.cache, oldest.Value.(*entry).id)
+			d.series.Remove(oldest)
+		}
+	}
+
+	e := &entry{
+		id:      id,
+		fields:  fields,
+		time:    t,
+		expires: time.Now().Add(d.DedupInterval.Duration),
+	}
+	d.cache[id] = d.series.PushBack(e)
+}
+
+// evictStale forgets the series that were passed on longer than the dedup
+// interval ago, as their next metric is passed on regardless.
+func (d *Dedup) evictStale(now time.Time) {
+	for el := d.series.Front(); el != nil; el = d.series.Front() {
+		e := el.Value.(*entry)
+		if now.Before(e.expires) {
+			return
+		}
+		delete(d.cache, e.id)
+		d.series.Remove(el)
+	}
+}
+
+func equal(a, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
+
+func init() {
+	processors.Add("dedup", func() telegraf.Processor {
+		return New()
+	})
+}
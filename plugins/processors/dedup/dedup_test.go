@@ -0,0 +1,90 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(host string, value int64, t time.Time) telegraf.Metric {
+	m, _ := metric.New("mem",
+		map[string]string{"host": host},
+		map[string]interface{}{"total": value},
+		t,
+	)
+	return m
+}
+
+func TestDropsUnchangedValues(t *testing.T) {
+	d := New()
+	now := time.Now()
+
+	assert.Len(t, d.Apply(newMetric("a", 1, now)), 1)
+	assert.Len(t, d.Apply(newMetric("a", 1, now.Add(10*time.Second))), 0)
+	assert.Len(t, d.Apply(newMetric("b", 1, now.Add(10*time.Second))), 1)
+	assert.Len(t, d.Apply(newMetric("a", 2, now.Add(20*time.Second))), 1)
+	assert.Len(t, d.Apply(newMetric("a", 2, now.Add(30*time.Second))), 0)
+}
+
+func TestChangedFieldSet(t *testing.T) {
+	d := New()
+	now := time.Now()
+
+	d.Apply(newMetric("a", 1, now))
+	m := newMetric("a", 1, now.Add(10*time.Second))
+	m.AddField("free", int64(1))
+	assert.Len(t, d.Apply(m), 1)
+}
+
+func TestHeartbeat(t *testing.T) {
+	d := New()
+	d.DedupInterval.Duration = time.Minute
+	now := time.Now()
+
+	assert.Len(t, d.Apply(newMetric("a", 1, now)), 1)
+	assert.Len(t, d.Apply(newMetric("a", 1, now.Add(50*time.Second))), 0)
+	assert.Len(t, d.Apply(newMetric("a", 1, now.Add(60*time.Second))), 1)
+	assert.Len(t, d.Apply(newMetric("a", 1, now.Add(70*time.Second))), 0)
+}
+
+func TestBatch(t *testing.T) {
+	d := New()
+	now := time.Now()
+
+	out := d.Apply(
+		newMetric("a", 1, now),
+		newMetric("a", 1, now.Add(time.Second)),
+		newMetric("b", 1, now),
+	)
+	require.Len(t, out, 2)
+	assert.Equal(t, "a", out[0].Tags()["host"])
+	assert.Equal(t, "b", out[1].Tags()["host"])
+}
+
+func TestMaxSeries(t *testing.T) {
+	d := New()
+	d.MaxSeries = 2
+	now := time.Now()
+
+	d.Apply(newMetric("a", 1, now), newMetric("b", 1, now), newMetric("c", 1, now))
+	assert.Equal(t, 2, d.series.Len())
+	assert.Len(t, d.cache, 2)
+
+	// the oldest series was forgotten, so its value is passed on again
+	assert.Len(t, d.Apply(newMetric("a", 1, now.Add(time.Second))), 1)
+	assert.Len(t, d.Apply(newMetric("c", 1, now.Add(time.Second))), 0)
+}
+
+func TestEvictStale(t *testing.T) {
+	d := New()
+	now := time.Now()
+
+	d.Apply(newMetric("a", 1, now), newMetric("b", 1, now))
+	d.evictStale(now.Add(d.DedupInterval.Duration + time.Second))
+	assert.Equal(t, 0, d.series.Len())
+	assert.Len(t, d.cache, 0)
+}
@@ -0,0 +1,122 @@
+package enum
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  [[processors.enum.mapping]]
+    ## Name of the field or the tag to map, set only one of them
+    field = "status"
+    # tag = "status"
+
+    ## Destination key, the mapped value replaces the original if not set
+    dest = "status_code"
+
+    ## Default value for values that have no mapping. Values that have no
+    ## mapping are left unchanged if this is not set.
+    # default = 0
+
+    ## Table of mappings
+    [processors.enum.mapping.value_mappings]
+      green = 1
+      yellow = 2
+      red = 3
+`
+
+type EnumMapper struct {
+	Mappings []Mapping `toml:"mapping"`
+}
+
+type Mapping struct {
+	Field         string
+	Tag           string
+	Dest          string
+	Default       interface{}
+	ValueMappings map[string]interface{}
+}
+
+func (mapper *EnumMapper) SampleConfig() string {
+	return sampleConfig
+}
+
+func (mapper *EnumMapper) Description() string {
+	return "Map enum values according to given table."
+}
+
+func (mapper *EnumMapper) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		for _, mapping := range mapper.Mappings {
+			if mapping.Field != "" {
+				mapper.applyField(metric, mapping)
+			}
+			if mapping.Tag != "" {
+				mapper.applyTag(metric, mapping)
+			}
+		}
+	}
+	return in
+}
+
+func (mapper *EnumMapper) applyField(metric telegraf.Metric, mapping Mapping) {
+	value, ok := metric.Fields()[mapping.Field].(string)
+	if !ok {
+		return
+	}
+	if mapped, ok := mapping.mapValue(value); ok {
+		metric.AddField(mapping.destination(mapping.Field), mapped)
+	}
+}
+
+func (mapper *EnumMapper) applyTag(metric telegraf.Metric, mapping Mapping) {
+	value, ok := metric.Tags()[mapping.Tag]
+	if !ok {
+		return
+	}
+	if mapped, ok := mapping.mapValue(value); ok {
+		metric.AddTag(mapping.destination(mapping.Tag), toString(mapped))
+	}
+}
+
+// mapValue returns the value mapped to the original one, or the default if
+// there is no mapping for it.
+func (mapping *Mapping) mapValue(original string) (interface{}, bool) {
+	if mapped, ok := mapping.ValueMappings[original]; ok {
+		return mapped, true
+	}
+	if mapping.Default != nil {
+		return mapping.Default, true
+	}
+	return nil, false
+}
+
+func (mapping *Mapping) destination(key string) string {
+	if mapping.Dest != "" {
+		return mapping.Dest
+	}
+	return key
+}
+
+func toString(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	return fmt.Sprint(v)
+}
+
+func init() {
+	processors.Add("enum", func() telegraf.Processor {
+		return &EnumMapper{}
+	})
+}
@@ -0,0 +1,98 @@
+package enum
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestMetric() telegraf.Metric {
+	m, _ := metric.New("m1",
+		map[string]string{"tag": "tag_value"},
+		map[string]interface{}{
+			"string_value": "test",
+			"int_value":    int64(13),
+			"true_value":   true,
+		},
+		time.Now(),
+	)
+	return m
+}
+
+func TestRetainsMetric(t *testing.T) {
+	mapper := EnumMapper{}
+	source := createTestMetric()
+
+	result := mapper.Apply(source)[0]
+	fields := result.Fields()
+
+	assert.Equal(t, "test", fields["string_value"])
+	assert.Equal(t, int64(13), fields["int_value"])
+	assert.Equal(t, true, fields["true_value"])
+	assert.Equal(t, "tag_value", result.Tags()["tag"])
+	assert.Equal(t, "m1", result.Name())
+}
+
+func TestMapsSingleStringValue(t *testing.T) {
+	mapper := EnumMapper{Mappings: []Mapping{{Field: "string_value", ValueMappings: map[string]interface{}{"test": int64(1)}}}}
+
+	fields := mapper.Apply(createTestMetric())[0].Fields()
+
+	assert.Equal(t, int64(1), fields["string_value"])
+}
+
+func TestNoFailureOnMappingsOnNonStringValuedFields(t *testing.T) {
+	mapper := EnumMapper{Mappings: []Mapping{{Field: "int_value", ValueMappings: map[string]interface{}{"13i": int64(7)}}}}
+
+	fields := mapper.Apply(createTestMetric())[0].Fields()
+
+	assert.Equal(t, int64(13), fields["int_value"])
+}
+
+func TestMapsToDefaultValueOnUnknownSourceValue(t *testing.T) {
+	mapper := EnumMapper{Mappings: []Mapping{{Field: "string_value", Default: int64(42), ValueMappings: map[string]interface{}{"other": int64(1)}}}}
+
+	fields := mapper.Apply(createTestMetric())[0].Fields()
+
+	assert.Equal(t, int64(42), fields["string_value"])
+}
+
+func TestDoNotMapToDefaultValueKnownSourceValue(t *testing.T) {
+	mapper := EnumMapper{Mappings: []Mapping{{Field: "string_value", Default: int64(42), ValueMappings: map[string]interface{}{"test": int64(1)}}}}
+
+	fields := mapper.Apply(createTestMetric())[0].Fields()
+
+	assert.Equal(t, int64(1), fields["string_value"])
+}
+
+func TestNoMappingWithoutDefaultOrDefinedMappingValue(t *testing.T) {
+	mapper := EnumMapper{Mappings: []Mapping{{Field: "string_value", ValueMappings: map[string]interface{}{"other": int64(1)}}}}
+
+	fields := mapper.Apply(createTestMetric())[0].Fields()
+
+	assert.Equal(t, "test", fields["string_value"])
+}
+
+func TestWritesToDestination(t *testing.T) {
+	mapper := EnumMapper{Mappings: []Mapping{{Field: "string_value", Dest: "string_code", ValueMappings: map[string]interface{}{"test": int64(1)}}}}
+
+	fields := mapper.Apply(createTestMetric())[0].Fields()
+
+	assert.Equal(t, "test", fields["string_value"])
+	assert.Equal(t, int64(1), fields["string_code"])
+}
+
+func TestMapsTags(t *testing.T) {
+	mapper := EnumMapper{Mappings: []Mapping{
+		{Tag: "tag", ValueMappings: map[string]interface{}{"tag_value": int64(1)}},
+		{Tag: "tag", Dest: "tag_state", Default: true},
+		{Tag: "missing", Default: "unknown"},
+	}}
+
+	tags := mapper.Apply(createTestMetric())[0].Tags()
+
+	assert.Equal(t, map[string]string{"tag": "1", "tag_state": "true"}, tags)
+}
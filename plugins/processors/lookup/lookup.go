@@ -0,0 +1,231 @@
+package lookup
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## File holding the lookup table, as CSV or JSON. The file is read again
+  ## when it changes.
+  file = "/etc/telegraf/hosts.csv"
+
+  ## Format of the file, "csv" or "json". It is guessed from the extension
+  ## of the file if not set.
+  # format = "csv"
+
+  ## Tag whose value is looked up in the table
+  key = "host"
+
+  ## Column of a CSV file that holds the key, the first column if not set.
+  # key_column = "host"
+
+  ## Columns of the table to add as tags. All columns are added if not set.
+  # dest_tags = ["owner", "team", "datacenter", "tier"]
+
+  ## How often to check the file for changes
+  # check_interval = "10s"
+`
+
+type Lookup struct {
+	File          string
+	Format        string
+	Key           string
+	KeyColumn     string            `toml:"key_column"`
+	DestTags      []string          `toml:"dest_tags"`
+	CheckInterval internal.Duration `toml:"check_interval"`
+
+	processors.ErrorReporter
+	table     map[string]map[string]string
+	modTime   time.Time
+	size      int64
+	lastCheck time.Time
+}
+
+func New() *Lookup {
+	return &Lookup{
+		CheckInterval: internal.Duration{Duration: 10 * time.Second},
+	}
+}
+
+func (l *Lookup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *Lookup) Description() string {
+	return "Add tags to metrics from a lookup table in a CSV or JSON file"
+}
+
+func (l *Lookup) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	l.checkFile()
+
+	for _, metric := range in {
+		value, ok := metric.Tags()[l.Key]
+		if !ok {
+			continue
+		}
+		for tag, v := range l.table[value] {
+			metric.AddTag(tag, v)
+		}
+	}
+	return in
+}
+
+// checkFile loads the table again if the file changed since it was last
+// loaded. The previous table is kept if the file can't be read.
+func (l *Lookup) checkFile() {
+	if !l.lastCheck.IsZero() && time.Since(l.lastCheck) < l.CheckInterval.Duration {
+		return
+	}
+	l.lastCheck = time.Now()
+
+	fi, err := os.Stat(l.File)
+	if err != nil {
+		l.AddError(fmt.Errorf("lookup: %s", err))
+		return
+	}
+	if fi.ModTime().Equal(l.modTime) && fi.Size() == l.size {
+		return
+	}
+	// an invalid file is only reported once, until it changes again
+	l.modTime, l.size = fi.ModTime(), fi.Size()
+
+	table, err := l.load()
+	if err != nil {
+		l.AddError(fmt.Errorf("lookup: error loading %s: %s", l.File, err))
+		return
+	}
+	if l.table != nil {
+		log.Printf("I! [processors.lookup] Reloaded %d entries from %s",
+			len(table), l.File)
+	}
+	l.table = table
+}
+
+func (l *Lookup) load() (map[string]map[string]string, error) {
+	format := l.Format
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(l.File), ".")
+	}
+
+	switch strings.ToLower(format) {
+	case "csv":
+		return l.loadCSV()
+	case "json":
+		return l.loadJSON()
+	}
+	return nil, fmt.Errorf("unknown format %q, must be \"csv\" or \"json\"", format)
+}
+
+func (l *Lookup) loadCSV() (map[string]map[string]string, error) {
+	f, err := os.Open(l.File)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header")
+	}
+
+	header := records[0]
+	keyIndex := 0
+	if l.KeyColumn != "" {
+		keyIndex = -1
+		for i, column := range header {
+			if column == l.KeyColumn {
+				keyIndex = i
+			}
+		}
+		if keyIndex == -1 {
+			return nil, fmt.Errorf("missing key column %q", l.KeyColumn)
+		}
+	}
+
+	columns := make(map[string]bool)
+	for _, column := range header {
+		columns[column] = true
+	}
+	for _, tag := range l.DestTags {
+		if !columns[tag] {
+			return nil, fmt.Errorf("missing column %q", tag)
+		}
+	}
+
+	table := make(map[string]map[string]string)
+	for _, record := range records[1:] {
+		row := make(map[string]interface{})
+		for i, value := range record {
+			if i != keyIndex && value != "" {
+				row[header[i]] = value
+			}
+		}
+		table[record[keyIndex]] = l.destTags(row)
+	}
+	return table, nil
+}
+
+func (l *Lookup) loadJSON() (map[string]map[string]string, error) {
+	buf, err := ioutil.ReadFile(l.File)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries map[string]map[string]interface{}
+	if err := json.Unmarshal(buf, &entries); err != nil {
+		return nil, err
+	}
+
+	table := make(map[string]map[string]string)
+	for key, entry := range entries {
+		table[key] = l.destTags(entry)
+	}
+	return table, nil
+}
+
+// destTags returns the values of a row that are added as tags.
+func (l *Lookup) destTags(row map[string]interface{}) map[string]string {
+	tags := make(map[string]string)
+	for column, value := range row {
+		if value == nil {
+			continue
+		}
+		if len(l.DestTags) == 0 || contains(l.DestTags, column) {
+			tags[column] = fmt.Sprint(value)
+		}
+	}
+	return tags
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	processors.Add("lookup", func() telegraf.Processor {
+		return New()
+	})
+}
@@ -0,0 +1,135 @@
+package lookup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(host string) telegraf.Metric {
+	m, _ := metric.New("cpu",
+		map[string]string{"host": host},
+		map[string]interface{}{"usage_idle": 99.0},
+		time.Now(),
+	)
+	return m
+}
+
+func TestLookupCSV(t *testing.T) {
+	l := New()
+	l.File = "testdata/hosts.csv"
+	l.Key = "host"
+
+	out := l.Apply(newMetric("server01"), newMetric("server02"), newMetric("server03"))
+
+	require.Len(t, out, 3)
+	assert.Equal(t, map[string]string{
+		"host":       "server01",
+		"owner":      "alice",
+		"team":       "db",
+		"datacenter": "us-east",
+		"tier":       "1",
+	}, out[0].Tags())
+	assert.Equal(t, map[string]string{
+		"host":       "server02",
+		"owner":      "bob",
+		"team":       "web",
+		"datacenter": "us-west",
+	}, out[1].Tags())
+	assert.Equal(t, map[string]string{"host": "server03"}, out[2].Tags())
+}
+
+func TestLookupJSON(t *testing.T) {
+	l := New()
+	l.File = "testdata/hosts.json"
+	l.Key = "host"
+	l.DestTags = []string{"team", "tier"}
+
+	out := l.Apply(newMetric("server01"), newMetric("server02"))
+
+	assert.Equal(t, map[string]string{
+		"host": "server01",
+		"team": "db",
+		"tier": "1",
+	}, out[0].Tags())
+	assert.Equal(t, map[string]string{
+		"host": "server02",
+		"team": "web",
+	}, out[1].Tags())
+}
+
+func TestLookupKeyColumn(t *testing.T) {
+	l := New()
+	l.File = "testdata/hosts.csv"
+	l.Format = "csv"
+	l.Key = "team"
+	l.KeyColumn = "team"
+	l.DestTags = []string{"owner"}
+
+	m, _ := metric.New("cpu", map[string]string{"team": "web"},
+		map[string]interface{}{"value": 1.0}, time.Now())
+	out := l.Apply(m)
+
+	assert.Equal(t, map[string]string{"team": "web", "owner": "bob"}, out[0].Tags())
+}
+
+func TestLookupErrors(t *testing.T) {
+	tests := []*Lookup{
+		{File: "testdata/missing.csv"},
+		{File: "testdata/hosts.csv", Format: "yaml"},
+		{File: "testdata/hosts.csv", KeyColumn: "name"},
+		{File: "testdata/hosts.csv", DestTags: []string{"rack"}},
+		{File: "testdata/hosts.csv", Format: "json"},
+	}
+
+	for _, l := range tests {
+		var acc testutil.Accumulator
+		l.Key = "host"
+		l.SetAccumulator(&acc)
+
+		out := l.Apply(newMetric("server01"))
+		assert.Equal(t, map[string]string{"host": "server01"}, out[0].Tags())
+		assert.Len(t, acc.Errors, 1)
+	}
+}
+
+func TestLookupReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "hosts.csv")
+	require.NoError(t, ioutil.WriteFile(file, []byte("host,team\nserver01,db\n"), 0644))
+
+	var acc testutil.Accumulator
+	l := New()
+	l.File = file
+	l.Key = "host"
+	l.SetAccumulator(&acc)
+
+	out := l.Apply(newMetric("server01"))
+	assert.Equal(t, "db", out[0].Tags()["team"])
+
+	// the file isn't checked again until the interval has passed
+	require.NoError(t, ioutil.WriteFile(file, []byte("host,team\nserver01,web\n"), 0644))
+	out = l.Apply(newMetric("server01"))
+	assert.Equal(t, "db", out[0].Tags()["team"])
+
+	l.lastCheck = time.Now().Add(-l.CheckInterval.Duration)
+	out = l.Apply(newMetric("server01"))
+	assert.Equal(t, "web", out[0].Tags()["team"])
+
+	// a broken file keeps the previous table
+	require.NoError(t, ioutil.WriteFile(file, []byte("host,team\nserver01,\"db\n"), 0644))
+	l.lastCheck = time.Now().Add(-l.CheckInterval.Duration)
+	out = l.Apply(newMetric("server01"))
+	assert.Equal(t, "web", out[0].Tags()["team"])
+	assert.Len(t, acc.Errors, 1)
+}
@@ -0,0 +1,56 @@
+package pivot
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Tag to use for naming the new field.
+  tag_key = "name"
+  ## Field to use as the value of the new field.
+  value_key = "value"
+`
+
+type Pivot struct {
+	TagKey   string `toml:"tag_key"`
+	ValueKey string `toml:"value_key"`
+}
+
+func (p *Pivot) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Pivot) Description() string {
+	return "Rotate a single valued metric into a multi field metric"
+}
+
+func (p *Pivot) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range metrics {
+		key, ok := m.Tags()[p.TagKey]
+		if !ok {
+			continue
+		}
+
+		value, ok := m.Fields()[p.ValueKey]
+		if !ok {
+			continue
+		}
+
+		m.RemoveTag(p.TagKey)
+		if key == p.ValueKey {
+			continue
+		}
+		// the new field is added first, as the last field of a metric can't
+		// be removed
+		m.AddField(key, value)
+		m.RemoveField(p.ValueKey)
+	}
+	return metrics
+}
+
+func init() {
+	processors.Add("pivot", func() telegraf.Processor {
+		return &Pivot{}
+	})
+}
@@ -0,0 +1,68 @@
+package pivot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("cpu", tags, fields, time.Unix(0, 0))
+	return m
+}
+
+func TestPivot(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     telegraf.Metric
+		tags   map[string]string
+		fields map[string]interface{}
+	}{
+		{
+			name:   "simple",
+			in:     newMetric(map[string]string{"name": "idle"}, map[string]interface{}{"value": 42.0}),
+			tags:   map[string]string{},
+			fields: map[string]interface{}{"idle": 42.0},
+		},
+		{
+			name: "other fields are kept",
+			in: newMetric(map[string]string{"name": "idle", "host": "a"},
+				map[string]interface{}{"value": 42.0, "count": int64(1)}),
+			tags:   map[string]string{"host": "a"},
+			fields: map[string]interface{}{"idle": 42.0, "count": int64(1)},
+		},
+		{
+			name:   "missing tag",
+			in:     newMetric(map[string]string{"host": "a"}, map[string]interface{}{"value": 42.0}),
+			tags:   map[string]string{"host": "a"},
+			fields: map[string]interface{}{"value": 42.0},
+		},
+		{
+			name:   "missing field",
+			in:     newMetric(map[string]string{"name": "idle"}, map[string]interface{}{"foo": 42.0}),
+			tags:   map[string]string{"name": "idle"},
+			fields: map[string]interface{}{"foo": 42.0},
+		},
+		{
+			name:   "same name",
+			in:     newMetric(map[string]string{"name": "value"}, map[string]interface{}{"value": 42.0}),
+			tags:   map[string]string{},
+			fields: map[string]interface{}{"value": 42.0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pivot := &Pivot{TagKey: "name", ValueKey: "value"}
+			out := pivot.Apply(tt.in)
+
+			require.Len(t, out, 1)
+			assert.Equal(t, tt.tags, out[0].Tags())
+			assert.Equal(t, tt.fields, out[0].Fields())
+		})
+	}
+}
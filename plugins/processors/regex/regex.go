@@ -0,0 +1,120 @@
+package regex
+
+import (
+	"log"
+	"regexp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+type Regex struct {
+	Measurement []converter
+	Tags        []converter
+	Fields      []converter
+
+	regexCache map[string]*regexp.Regexp
+}
+
+type converter struct {
+	Key         string
+	Pattern     string
+	Replacement string
+	ResultKey   string
+}
+
+var sampleConfig = `
+  ## The rules of each kind are applied in order, first to the measurement
+  ## name, then to the tags and then to the fields.
+
+  ## Rename measurements like "nginx_requests_v2" to "nginx_requests"
+  # [[processors.regex.measurement]]
+  #   pattern = "^(\\w+)_v\\d+$"
+  #   replacement = "${1}"
+
+  ## Tag and field conversion defines a conversion rule for one key:
+  ##  - key: key of the tag or string field to convert
+  ##  - pattern: regular expression the value must match to be converted
+  ##  - replacement: replacement of the value, which may refer to groups of
+  ##    the pattern as ${1} or ${name}
+  ##  - result_key: if set, store the result under this key and leave the
+  ##    original value untouched
+  # [[processors.regex.tags]]
+  #   key = "resp_code"
+  #   pattern = "^(\\d)\\d\\d$"
+  #   replacement = "${1}xx"
+
+  # [[processors.regex.fields]]
+  #   key = "request"
+  #   pattern = "^/api(?P<method>/[\\w/]+)\\S*"
+  #   replacement = "${method}"
+  #   result_key = "method"
+`
+
+func (r *Regex) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Regex) Description() string {
+	return "Transforms tag and field values and measurement names with regex pattern"
+}
+
+func (r *Regex) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		for _, c := range r.Measurement {
+			if value, ok := r.convert(c, metric.Name()); ok {
+				metric.SetName(value)
+			}
+		}
+
+		for _, c := range r.Tags {
+			if !metric.HasTag(c.Key) {
+				continue
+			}
+			if value, ok := r.convert(c, metric.Tags()[c.Key]); ok {
+				metric.AddTag(resultKey(c), value)
+			}
+		}
+
+		for _, c := range r.Fields {
+			if s, ok := metric.Fields()[c.Key].(string); ok {
+				if value, ok := r.convert(c, s); ok {
+					metric.AddField(resultKey(c), value)
+				}
+			}
+		}
+	}
+	return in
+}
+
+// convert returns the value with the replacement applied, and false if the
+// pattern doesn't match it.
+func (r *Regex) convert(c converter, value string) (string, bool) {
+	regex, ok := r.regexCache[c.Pattern]
+	if !ok {
+		var err error
+		regex, err = regexp.Compile(c.Pattern)
+		if err != nil {
+			log.Printf("E! [processors.regex] Invalid pattern %q: %s", c.Pattern, err)
+		}
+		// invalid patterns are cached as nil, so they are only reported once
+		r.regexCache[c.Pattern] = regex
+	}
+	if regex == nil || !regex.MatchString(value) {
+		return "", false
+	}
+	return regex.ReplaceAllString(value, c.Replacement), true
+}
+
+func resultKey(c converter) string {
+	if c.ResultKey != "" {
+		return c.ResultKey
+	}
+	return c.Key
+}
+
+func init() {
+	processors.Add("regex", func() telegraf.Processor {
+		return &Regex{regexCache: make(map[string]*regexp.Regexp)}
+	})
+}
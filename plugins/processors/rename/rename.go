@@ -0,0 +1,249 @@
+package rename
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## What to do if a tag or field is renamed to a key the metric already
+  ## has: "overwrite" the existing value, "keep" the existing value and leave
+  ## the key unrenamed, or report an "error" and leave the key unrenamed.
+  # on_conflict = "overwrite"
+
+  ## Each rule renames the measurements, tags or fields matching a glob. Every
+  ## wildcard of the glob is captured, and can be used in dest as ${1}, ${2}
+  ## and so on. Only the first matching rule is applied to each key.
+  # [[processors.rename.replace]]
+  #   measurement = "network_interface_throughput"
+  #   dest = "throughput"
+
+  # [[processors.rename.replace]]
+  #   tag = "hostname"
+  #   dest = "host"
+
+  # [[processors.rename.replace]]
+  #   field = "*_bytes_sent"
+  #   dest = "${1}_sent_bytes"
+`
+
+type Replace struct {
+	Measurement string `toml:"measurement"`
+	Tag         string `toml:"tag"`
+	Field       string `toml:"field"`
+	Dest        string `toml:"dest"`
+}
+
+type Rename struct {
+	Replaces   []Replace `toml:"replace"`
+	OnConflict string    `toml:"on_conflict"`
+
+	processors.ErrorReporter
+	measurement []rule
+	tags        []rule
+	fields      []rule
+}
+
+type rule struct {
+	re   *regexp.Regexp
+	dest string
+}
+
+func (r *Rename) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Rename) Description() string {
+	return "Rename measurements, tags, and fields that pass through this filter."
+}
+
+func (r *Rename) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !r.Initialize(r.compile) {
+		return in
+	}
+
+	for _, metric := range in {
+		if dest, ok := rename(r.measurement, metric.Name()); ok {
+			metric.SetName(dest)
+		}
+		r.renameTags(metric)
+		r.renameFields(metric)
+	}
+	return in
+}
+
+func (r *Rename) compile() error {
+	switch r.OnConflict {
+	case "", "overwrite", "keep", "error":
+	default:
+		return fmt.Errorf("rename: invalid on_conflict %q, must be one of "+
+			"\"overwrite\", \"keep\" or \"error\"", r.OnConflict)
+	}
+
+	for _, replace := range r.Replaces {
+		var glob string
+		var rules *[]rule
+		n := 0
+		if replace.Measurement != "" {
+			glob, rules = replace.Measurement, &r.measurement
+			n++
+		}
+		if replace.Tag != "" {
+			glob, rules = replace.Tag, &r.tags
+			n++
+		}
+		if replace.Field != "" {
+			glob, rules = replace.Field, &r.fields
+			n++
+		}
+		if n != 1 {
+			return fmt.Errorf("rename: replace with dest %q must have exactly one "+
+				"of measurement, tag or field", replace.Dest)
+		}
+		if replace.Dest == "" {
+			return fmt.Errorf("rename: replace of %q must have a dest", glob)
+		}
+
+		re, err := compileGlob(glob)
+		if err != nil {
+			return fmt.Errorf("rename: invalid glob %q: %s", glob, err)
+		}
+		*rules = append(*rules, rule{re: re, dest: replace.Dest})
+	}
+	return nil
+}
+
+// compileGlob turns a glob into an anchored regular expression, that
+// captures each wildcard as a group: "*" and "?" match any number of
+// characters or a single one, and "[...]" one of a class of characters. A
+// backslash matches the character following it literally.
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	var buf bytes.Buffer
+	buf.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			buf.WriteString("(.*)")
+		case '?':
+			buf.WriteString("(.)")
+		case '[':
+			j := i + 1
+			if j < len(glob) && glob[j] == '!' {
+				j++
+			}
+			// a closing bracket right at the start is part of the class
+			if j < len(glob) && glob[j] == ']' {
+				j++
+			}
+			for j < len(glob) && glob[j] != ']' {
+				j++
+			}
+			if j == len(glob) {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+			class := glob[i+1 : j]
+			if len(class) > 0 && class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("([" + class + "])")
+			i = j
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			buf.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	buf.WriteString("$")
+	return regexp.Compile(buf.String())
+}
+
+// rename returns the new name of key by the first rule matching it.
+func rename(rules []rule, key string) (string, bool) {
+	for _, rule := range rules {
+		if rule.re.MatchString(key) {
+			dest := rule.re.ReplaceAllString(key, rule.dest)
+			return dest, dest != key
+		}
+	}
+	return "", false
+}
+
+func (r *Rename) renameTags(metric telegraf.Metric) {
+	if len(r.tags) == 0 {
+		return
+	}
+
+	tags := metric.Tags()
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		dest, ok := rename(r.tags, key)
+		if !ok {
+			continue
+		}
+		if metric.HasTag(dest) && !r.resolveConflict(metric, "tag", key, dest) {
+			continue
+		}
+		metric.RemoveTag(key)
+		metric.AddTag(dest, tags[key])
+	}
+}
+
+func (r *Rename) renameFields(metric telegraf.Metric) {
+	if len(r.fields) == 0 {
+		return
+	}
+
+	fields := metric.Fields()
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		dest, ok := rename(r.fields, key)
+		if !ok {
+			continue
+		}
+		if metric.HasField(dest) && !r.resolveConflict(metric, "field", key, dest) {
+			continue
+		}
+		// the new field is added first, as the last field of a metric can't
+		// be removed
+		metric.AddField(dest, fields[key])
+		metric.RemoveField(key)
+	}
+}
+
+// resolveConflict returns true if key may be renamed to dest although the
+// metric already has it.
+func (r *Rename) resolveConflict(metric telegraf.Metric, kind, key, dest string) bool {
+	switch r.OnConflict {
+	case "keep":
+		return false
+	case "error":
+		r.AddError(fmt.Errorf("rename: cannot rename %s %q of %s to %q, which already exists",
+			kind, key, metric.Name(), dest))
+		return false
+	}
+	return true
+}
+
+func init() {
+	processors.Add("rename", func() telegraf.Processor {
+		return &Rename{}
+	})
+}
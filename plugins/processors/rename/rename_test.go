@@ -0,0 +1,195 @@
+package rename
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(name string, tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	if fields == nil {
+		fields = map[string]interface{}{}
+	}
+	m, _ := metric.New(name, tags, fields, time.Now())
+	return m
+}
+
+func TestMeasurementRename(t *testing.T) {
+	r := &Rename{
+		Replaces: []Replace{
+			{Measurement: "foo", Dest: "bar"},
+			{Measurement: "baz", Dest: "quux"},
+			{Measurement: "network_*_throughput", Dest: "net_${1}"},
+		},
+	}
+	m1 := newMetric("foo", nil, map[string]interface{}{"value": 1})
+	m2 := newMetric("bar", nil, map[string]interface{}{"value": 1})
+	m3 := newMetric("baz", nil, map[string]interface{}{"value": 1})
+	m4 := newMetric("network_interface_throughput", nil, map[string]interface{}{"value": 1})
+	results := r.Apply(m1, m2, m3, m4)
+	assert.Equal(t, "bar", results[0].Name())
+	assert.Equal(t, "bar", results[1].Name())
+	assert.Equal(t, "quux", results[2].Name())
+	assert.Equal(t, "net_interface", results[3].Name())
+}
+
+func TestTagRename(t *testing.T) {
+	r := &Rename{
+		Replaces: []Replace{
+			{Tag: "hostname", Dest: "host"},
+			{Tag: "k8s_?_*", Dest: "kubernetes_${2}_${1}"},
+		},
+	}
+	m := newMetric("foo",
+		map[string]string{"hostname": "localhost", "k8s_a_pod": "web", "region": "east-1"},
+		map[string]interface{}{"value": 1})
+
+	results := r.Apply(m)
+
+	assert.Equal(t, map[string]string{
+		"host":             "localhost",
+		"kubernetes_pod_a": "web",
+		"region":           "east-1",
+	}, results[0].Tags())
+}
+
+func TestFieldRename(t *testing.T) {
+	r := &Rename{
+		Replaces: []Replace{
+			{Field: "time_msec", Dest: "time"},
+			{Field: "*_bytes_[rs]*", Dest: "${1}_${2}${3}_bytes"},
+		},
+	}
+	m := newMetric("foo", nil, map[string]interface{}{
+		"time_msec":       int64(1250),
+		"eth0_bytes_sent": int64(10),
+		"eth0_bytes_recv": int64(20),
+	})
+
+	results := r.Apply(m)
+
+	assert.Equal(t, map[string]interface{}{
+		"time":            int64(1250),
+		"eth0_sent_bytes": int64(10),
+		"eth0_recv_bytes": int64(20),
+	}, results[0].Fields())
+}
+
+func TestFirstRuleWins(t *testing.T) {
+	r := &Rename{
+		Replaces: []Replace{
+			{Field: "value", Dest: "first"},
+			{Field: "*", Dest: "second"},
+		},
+	}
+	results := r.Apply(newMetric("foo", nil, map[string]interface{}{"value": 1}))
+	assert.Equal(t, map[string]interface{}{"first": int64(1)}, results[0].Fields())
+}
+
+func TestConflicts(t *testing.T) {
+	tests := []struct {
+		onConflict string
+		tags       map[string]string
+		fields     map[string]interface{}
+		errors     int
+	}{
+		{
+			onConflict: "",
+			tags:       map[string]string{"host": "a"},
+			fields:     map[string]interface{}{"value": int64(1)},
+		},
+		{
+			onConflict: "overwrite",
+			tags:       map[string]string{"host": "a"},
+			fields:     map[string]interface{}{"value": int64(1)},
+		},
+		{
+			onConflict: "keep",
+			tags:       map[string]string{"host": "b", "hostname": "a"},
+			fields:     map[string]interface{}{"value": int64(2), "val": int64(1)},
+		},
+		{
+			onConflict: "error",
+			tags:       map[string]string{"host": "b", "hostname": "a"},
+			fields:     map[string]interface{}{"value": int64(2), "val": int64(1)},
+			errors:     2,
+		},
+	}
+
+	for _, tt := range tests {
+		var acc testutil.Accumulator
+		r := &Rename{
+			OnConflict: tt.onConflict,
+			Replaces: []Replace{
+				{Tag: "hostname", Dest: "host"},
+				{Field: "val", Dest: "value"},
+			},
+		}
+		r.SetAccumulator(&acc)
+		m := newMetric("foo",
+			map[string]string{"hostname": "a", "host": "b"},
+			map[string]interface{}{"val": int64(1), "value": int64(2)})
+
+		results := r.Apply(m)
+
+		assert.Equal(t, tt.tags, results[0].Tags(), tt.onConflict)
+		assert.Equal(t, tt.fields, results[0].Fields(), tt.onConflict)
+		assert.Len(t, acc.Errors, tt.errors, tt.onConflict)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	tests := []*Rename{
+		{OnConflict: "replace"},
+		{Replaces: []Replace{{Dest: "foo"}}},
+		{Replaces: []Replace{{Tag: "foo", Field: "foo", Dest: "bar"}}},
+		{Replaces: []Replace{{Tag: "[foo", Dest: "bar"}}},
+		{Replaces: []Replace{{Field: "foo"}}},
+	}
+
+	for _, r := range tests {
+		var acc testutil.Accumulator
+		r.SetAccumulator(&acc)
+		r.Replaces = append(r.Replaces, Replace{Tag: "host", Dest: "hostname"})
+		m := newMetric("foo", map[string]string{"host": "a"},
+			map[string]interface{}{"value": 1})
+
+		results := r.Apply(m)
+		results = r.Apply(results...)
+		require.Len(t, acc.Errors, 1)
+		assert.Equal(t, map[string]string{"host": "a"}, results[0].Tags())
+	}
+}
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		glob    string
+		key     string
+		matches bool
+	}{
+		{"foo", "foo", true},
+		{"foo", "foobar", false},
+		{"foo.bar", "fooxbar", false},
+		{"foo*", "foobar", true},
+		{"f?o", "foo", true},
+		{"f?o", "fooo", false},
+		{"[!a]*", "abc", false},
+		{"[!a]*", "bc", true},
+		{`\*`, "*", true},
+		{`\*`, "a", false},
+	}
+
+	for _, tt := range tests {
+		re, err := compileGlob(tt.glob)
+		require.NoError(t, err, tt.glob)
+		assert.Equal(t, tt.matches, re.MatchString(tt.key), tt.glob)
+	}
+}
@@ -0,0 +1,44 @@
+package processors
+
+import (
+	"log"
+
+	"github.com/influxdata/telegraf"
+)
+
+// ErrorReporter implements telegraf.ErrorReportingProcessor for the
+// processors embedding it. Errors are reported to the accumulator set by the
+// agent, or logged if there is none, ie in tests.
+type ErrorReporter struct {
+	acc         telegraf.Accumulator
+	initialized bool
+	initErr     error
+}
+
+func (r *ErrorReporter) SetAccumulator(acc telegraf.Accumulator) {
+	r.acc = acc
+}
+
+// AddError reports an error of the processor.
+func (r *ErrorReporter) AddError(err error) {
+	if r.acc != nil {
+		r.acc.AddError(err)
+		return
+	}
+	log.Printf("E! [processors] %s", err)
+}
+
+// Initialize calls init the first time it is called, ie to compile the
+// configuration of the processor, and reports the error it returns once.
+// It returns false if init failed, in which case the processor passes the
+// metrics on unchanged.
+func (r *ErrorReporter) Initialize(init func() error) bool {
+	if !r.initialized {
+		r.initialized = true
+		r.initErr = init()
+		if r.initErr != nil {
+			r.AddError(r.initErr)
+		}
+	}
+	return r.initErr == nil
+}
@@ -0,0 +1,28 @@
+package processors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitializeReportsOnce(t *testing.T) {
+	acc := testutil.Accumulator{}
+	r := ErrorReporter{}
+	r.SetAccumulator(&acc)
+
+	calls := 0
+	compile := func() error {
+		calls++
+		return errors.New("invalid")
+	}
+	assert.False(t, r.Initialize(compile))
+	assert.False(t, r.Initialize(compile))
+	assert.Equal(t, 1, calls)
+	assert.Len(t, acc.Errors, 1)
+
+	r = ErrorReporter{}
+	assert.True(t, r.Initialize(func() error { return nil }))
+}
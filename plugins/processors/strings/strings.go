@@ -0,0 +1,254 @@
+package strings
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+type Strings struct {
+	Lowercase  []converter `toml:"lowercase"`
+	Uppercase  []converter `toml:"uppercase"`
+	Trim       []converter `toml:"trim"`
+	TrimLeft   []converter `toml:"trim_left"`
+	TrimRight  []converter `toml:"trim_right"`
+	TrimPrefix []converter `toml:"trim_prefix"`
+	TrimSuffix []converter `toml:"trim_suffix"`
+	Replace    []converter `toml:"replace"`
+	Truncate   []converter `toml:"truncate"`
+
+	processors.ErrorReporter
+	converters []converter
+}
+
+type converter struct {
+	Field       string
+	Tag         string
+	Measurement string
+	Dest        string
+	Cutset      string
+	Prefix      string
+	Suffix      string
+	Old         string
+	New         string
+	Width       int
+
+	fieldFilter       filter.Filter
+	tagFilter         filter.Filter
+	measurementFilter filter.Filter
+	fn                func(string) string
+}
+
+const sampleConfig = `
+  ## Each conversion selects the measurement names, tags or string fields it
+  ## applies to with a glob. The conversions are applied in the order they
+  ## are listed here, regardless of their order in the configuration.
+  ## Setting dest stores the result in a new tag or field.
+
+  ## Convert a tag value to lowercase
+  # [[processors.strings.lowercase]]
+  #   tag = "method"
+
+  ## Convert a field value to uppercase and store in a new field
+  # [[processors.strings.uppercase]]
+  #   field = "uri_stem"
+  #   dest = "uri_stem_normalised"
+
+  ## Trim leading and trailing whitespace using the default cutset
+  # [[processors.strings.trim]]
+  #   field = "message"
+
+  ## Trim leading characters in cutset
+  # [[processors.strings.trim_left]]
+  #   field = "message"
+  #   cutset = "\t"
+
+  ## Trim trailing characters in cutset
+  # [[processors.strings.trim_right]]
+  #   field = "message"
+  #   cutset = "\r\n"
+
+  ## Trim the given prefix from the field
+  # [[processors.strings.trim_prefix]]
+  #   field = "my_value"
+  #   prefix = "my_"
+
+  ## Trim the given suffix from the field
+  # [[processors.strings.trim_suffix]]
+  #   field = "read_count"
+  #   suffix = "_count"
+
+  ## Replace all non-overlapping instances of old with new
+  # [[processors.strings.replace]]
+  #   measurement = "*"
+  #   old = ":"
+  #   new = "_"
+
+  ## Truncate values to at most width bytes
+  # [[processors.strings.truncate]]
+  #   tag = "*"
+  #   width = 64
+`
+
+func (s *Strings) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Strings) Description() string {
+	return "Perform string processing on tags, fields, and measurements"
+}
+
+func (s *Strings) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !s.Initialize(s.compile) {
+		return in
+	}
+
+	for _, metric := range in {
+		for _, c := range s.converters {
+			c.convertMeasurement(metric)
+			c.convertTags(metric)
+			c.convertFields(metric)
+		}
+	}
+	return in
+}
+
+func (s *Strings) compile() error {
+	add := func(kind string, cs []converter, fn func(c converter) func(string) string) error {
+		for _, c := range cs {
+			var err error
+			if c.fieldFilter, err = filter.Compile(nonEmpty(c.Field)); err != nil {
+				return fmt.Errorf("strings: invalid %s field %q: %s", kind, c.Field, err)
+			}
+			if c.tagFilter, err = filter.Compile(nonEmpty(c.Tag)); err != nil {
+				return fmt.Errorf("strings: invalid %s tag %q: %s", kind, c.Tag, err)
+			}
+			if c.measurementFilter, err = filter.Compile(nonEmpty(c.Measurement)); err != nil {
+				return fmt.Errorf("strings: invalid %s measurement %q: %s",
+					kind, c.Measurement, err)
+			}
+			c.fn = fn(c)
+			s.converters = append(s.converters, c)
+		}
+		return nil
+	}
+
+	steps := []struct {
+		kind string
+		cs   []converter
+		fn   func(c converter) func(string) string
+	}{
+		{"lowercase", s.Lowercase, func(c converter) func(string) string {
+			return strings.ToLower
+		}},
+		{"uppercase", s.Uppercase, func(c converter) func(string) string {
+			return strings.ToUpper
+		}},
+		{"trim", s.Trim, func(c converter) func(string) string {
+			if c.Cutset == "" {
+				return strings.TrimSpace
+			}
+			return func(v string) string { return strings.Trim(v, c.Cutset) }
+		}},
+		{"trim_left", s.TrimLeft, func(c converter) func(string) string {
+			if c.Cutset == "" {
+				return func(v string) string { return strings.TrimLeftFunc(v, unicode.IsSpace) }
+			}
+			return func(v string) string { return strings.TrimLeft(v, c.Cutset) }
+		}},
+		{"trim_right", s.TrimRight, func(c converter) func(string) string {
+			if c.Cutset == "" {
+				return func(v string) string { return strings.TrimRightFunc(v, unicode.IsSpace) }
+			}
+			return func(v string) string { return strings.TrimRight(v, c.Cutset) }
+		}},
+		{"trim_prefix", s.TrimPrefix, func(c converter) func(string) string {
+			return func(v string) string { return strings.TrimPrefix(v, c.Prefix) }
+		}},
+		{"trim_suffix", s.TrimSuffix, func(c converter) func(string) string {
+			return func(v string) string { return strings.TrimSuffix(v, c.Suffix) }
+		}},
+		{"replace", s.Replace, func(c converter) func(string) string {
+			return func(v string) string { return strings.Replace(v, c.Old, c.New, -1) }
+		}},
+		{"truncate", s.Truncate, func(c converter) func(string) string {
+			return func(v string) string { return truncate(v, c.Width) }
+		}},
+	}
+
+	for _, step := range steps {
+		if err := add(step.kind, step.cs, step.fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *converter) convertMeasurement(metric telegraf.Metric) {
+	if c.measurementFilter == nil || !c.measurementFilter.Match(metric.Name()) {
+		return
+	}
+	metric.SetName(c.fn(metric.Name()))
+}
+
+func (c *converter) convertTags(metric telegraf.Metric) {
+	if c.tagFilter == nil {
+		return
+	}
+	for key, value := range metric.Tags() {
+		if !c.tagFilter.Match(key) {
+			continue
+		}
+		metric.AddTag(c.destination(key), c.fn(value))
+	}
+}
+
+func (c *converter) convertFields(metric telegraf.Metric) {
+	if c.fieldFilter == nil {
+		return
+	}
+	for key, value := range metric.Fields() {
+		if !c.fieldFilter.Match(key) {
+			continue
+		}
+		if s, ok := value.(string); ok {
+			metric.AddField(c.destination(key), c.fn(s))
+		}
+	}
+}
+
+func (c *converter) destination(key string) string {
+	if c.Dest != "" {
+		return c.Dest
+	}
+	return key
+}
+
+// truncate cuts s to at most width bytes, without splitting a character.
+func truncate(s string, width int) string {
+	if width < 0 || len(s) <= width {
+		return s
+	}
+	for width > 0 && !utf8.RuneStart(s[width]) {
+		width--
+	}
+	return s[:width]
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
+
+func init() {
+	processors.Add("strings", func() telegraf.Processor {
+		return &Strings{}
+	})
+}
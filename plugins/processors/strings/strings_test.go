@@ -0,0 +1,171 @@
+package strings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newM1() telegraf.Metric {
+	m1, _ := metric.New("IIS_log",
+		map[string]string{
+			"verb":           "GET",
+			"s-computername": "MIXEDCASE_hostname",
+		},
+		map[string]interface{}{
+			"request":    "/mixed/CASE/paTH/?from=-1D&to=now",
+			"whitespace": "  whitespace\t",
+			"count":      int64(3),
+		},
+		time.Now(),
+	)
+	return m1
+}
+
+func TestFieldConversions(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *Strings
+		check  func(t *testing.T, actual telegraf.Metric)
+	}{
+		{
+			name: "Should change existing field to lowercase",
+			plugin: &Strings{
+				Lowercase: []converter{{Field: "request"}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				assert.Equal(t, "/mixed/case/path/?from=-1d&to=now", actual.Fields()["request"])
+			},
+		},
+		{
+			name: "Should change existing field to uppercase",
+			plugin: &Strings{
+				Uppercase: []converter{{Field: "request"}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				assert.Equal(t, "/MIXED/CASE/PATH/?FROM=-1D&TO=NOW", actual.Fields()["request"])
+			},
+		},
+		{
+			name: "Should add new lowercase field",
+			plugin: &Strings{
+				Lowercase: []converter{{Field: "request", Dest: "lowercase_request"}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				fv := actual.Fields()
+				assert.Equal(t, "/mixed/CASE/paTH/?from=-1D&to=now", fv["request"])
+				assert.Equal(t, "/mixed/case/path/?from=-1d&to=now", fv["lowercase_request"])
+			},
+		},
+		{
+			name: "Should trim from both sides",
+			plugin: &Strings{
+				Trim: []converter{{Field: "request", Cutset: "/w"}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				assert.Equal(t, "mixed/CASE/paTH/?from=-1D&to=no", actual.Fields()["request"])
+			},
+		},
+		{
+			name: "Should trim whitespace by default",
+			plugin: &Strings{
+				Trim:      []converter{{Field: "whitespace", Dest: "both"}},
+				TrimLeft:  []converter{{Field: "whitespace", Dest: "left"}},
+				TrimRight: []converter{{Field: "whitespace", Dest: "right"}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				fv := actual.Fields()
+				assert.Equal(t, "whitespace", fv["both"])
+				assert.Equal(t, "whitespace\t", fv["left"])
+				assert.Equal(t, "  whitespace", fv["right"])
+			},
+		},
+		{
+			name: "Should trim prefix and suffix",
+			plugin: &Strings{
+				TrimPrefix: []converter{{Field: "request", Prefix: "/mixed"}},
+				TrimSuffix: []converter{{Field: "request", Suffix: "&to=now"}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				assert.Equal(t, "/CASE/paTH/?from=-1D", actual.Fields()["request"])
+			},
+		},
+		{
+			name: "Should replace and truncate",
+			plugin: &Strings{
+				Replace:  []converter{{Field: "request", Old: "/", New: "."}},
+				Truncate: []converter{{Field: "request", Width: 6}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				assert.Equal(t, ".mixed", actual.Fields()["request"])
+			},
+		},
+		{
+			name: "Should ignore fields that are not strings",
+			plugin: &Strings{
+				Truncate: []converter{{Field: "*", Width: 1}},
+			},
+			check: func(t *testing.T, actual telegraf.Metric) {
+				fv := actual.Fields()
+				assert.Equal(t, "/", fv["request"])
+				assert.Equal(t, " ", fv["whitespace"])
+				assert.Equal(t, int64(3), fv["count"])
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := tt.plugin.Apply(newM1())
+			assert.Len(t, metrics, 1)
+			tt.check(t, metrics[0])
+		})
+	}
+}
+
+func TestTagAndMeasurementConversions(t *testing.T) {
+	plugin := &Strings{
+		Lowercase: []converter{
+			{Tag: "s-*"},
+			{Measurement: "*"},
+		},
+		Replace: []converter{
+			{Measurement: "iis_*", Old: "_", New: "-"},
+		},
+	}
+
+	metrics := plugin.Apply(newM1())
+
+	assert.Equal(t, "iis-log", metrics[0].Name())
+	assert.Equal(t, map[string]string{
+		"verb":           "GET",
+		"s-computername": "mixedcase_hostname",
+	}, metrics[0].Tags())
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 5))
+	assert.Equal(t, "ab", truncate("abc", 2))
+	assert.Equal(t, "", truncate("abc", 0))
+	// the two byte character is not split
+	assert.Equal(t, "a", truncate("aéb", 2))
+	assert.Equal(t, "aé", truncate("aéb", 3))
+}
+
+func TestInvalidGlob(t *testing.T) {
+	var acc testutil.Accumulator
+	plugin := &Strings{
+		Lowercase: []converter{{Tag: "[verb"}},
+	}
+	plugin.SetAccumulator(&acc)
+
+	plugin.Apply(newM1())
+	metrics := plugin.Apply(newM1())
+
+	assert.Equal(t, "GET", metrics[0].Tags()["verb"])
+	assert.Len(t, acc.Errors, 1)
+}
@@ -0,0 +1,275 @@
+package topk
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## How long to buffer metrics before ranking them and passing on the top
+  ## groups. The buffered metrics are also passed on when Telegraf stops.
+  period = "10s"
+
+  ## How many groups to pass on
+  k = 10
+
+  ## Tags (globs allowed) to group the metrics by, along with their
+  ## measurement name. Metrics that have the same measurement and the same
+  ## values for the matching tags are in the same group.
+  group_by = ["*"]
+
+  ## Fields to rank the groups by. A group is passed on if it is in the top
+  ## k for any of them.
+  fields = ["value"]
+
+  ## How the values of a field are aggregated across a group to rank it:
+  ## "sum", "mean", "max" or "min".
+  aggregation = "mean"
+
+  ## Pass on the bottom k groups instead of the top k
+  bottomk = false
+
+  ## If set, tag each metric with the rank of its group, starting at 1. If
+  ## the group is ranked by several fields, its best rank is used.
+  # add_rank_tag = "rank"
+`
+
+type TopK struct {
+	Period      internal.Duration
+	K           int
+	GroupBy     []string `toml:"group_by"`
+	Fields      []string
+	Aggregation string
+	Bottomk     bool
+	AddRankTag  string `toml:"add_rank_tag"`
+
+	processors.ErrorReporter
+	tagsFilter      filter.Filter
+	aggregate       func(values []float64) float64
+	cache           map[string][]telegraf.Metric
+	lastAggregation time.Time
+}
+
+func New() *TopK {
+	return &TopK{
+		Period:      internal.Duration{Duration: 10 * time.Second},
+		K:           10,
+		GroupBy:     []string{"*"},
+		Fields:      []string{"value"},
+		Aggregation: "mean",
+	}
+}
+
+func (t *TopK) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *TopK) Description() string {
+	return "Pass on the metrics of the top k groups, ranked by an aggregation of their fields"
+}
+
+func (t *TopK) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !t.Initialize(t.compile) {
+		return in
+	}
+
+	for _, m := range in {
+		key := t.groupKey(m)
+		t.cache[key] = append(t.cache[key], m)
+	}
+
+	if time.Since(t.lastAggregation) >= t.Period.Duration {
+		return t.push()
+	}
+	return []telegraf.Metric{}
+}
+
+// Flush passes on the buffered metrics once the period has passed, even if
+// no metrics arrived since, and when the agent stops.
+func (t *TopK) Flush(final bool) []telegraf.Metric {
+	if len(t.cache) == 0 {
+		return nil
+	}
+	if !final && time.Since(t.lastAggregation) < t.Period.Duration {
+		return nil
+	}
+	return t.push()
+}
+
+func (t *TopK) compile() error {
+	t.cache = make(map[string][]telegraf.Metric)
+	t.lastAggregation = time.Now()
+
+	var err error
+	t.tagsFilter, err = filter.Compile(t.GroupBy)
+	if err != nil {
+		return fmt.Errorf("topk: invalid group_by: %s", err)
+	}
+
+	switch t.Aggregation {
+	case "sum":
+		t.aggregate = sum
+	case "mean":
+		t.aggregate = func(values []float64) float64 {
+			return sum(values) / float64(len(values))
+		}
+	case "max":
+		t.aggregate = func(values []float64) float64 {
+			max := values[0]
+			for _, v := range values[1:] {
+				if v > max {
+					max = v
+				}
+			}
+			return max
+		}
+	case "min":
+		t.aggregate = func(values []float64) float64 {
+			min := values[0]
+			for _, v := range values[1:] {
+				if v < min {
+					min = v
+				}
+			}
+			return min
+		}
+	default:
+		return fmt.Errorf("topk: invalid aggregation %q, must be one of "+
+			"\"sum\", \"mean\", \"max\" or \"min\"", t.Aggregation)
+	}
+
+	if t.K < 1 {
+		return fmt.Errorf("topk: k must be at least 1, got %d", t.K)
+	}
+	return nil
+}
+
+// groupKey identifies the group of a metric by its measurement and the
+// values of the tags it is grouped by.
+func (t *TopK) groupKey(m telegraf.Metric) string {
+	tags := m.Tags()
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		if t.tagsFilter != nil && t.tagsFilter.Match(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	parts := []string{m.Name()}
+	for _, key := range keys {
+		parts = append(parts, key+"="+tags[key])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// push ranks the buffered groups, and returns the metrics of the top k of
+// them for any of the fields. The other metrics are dropped.
+func (t *TopK) push() []telegraf.Metric {
+	t.lastAggregation = time.Now()
+
+	// best rank of each passed group, starting at 1
+	ranks := make(map[string]int)
+	for _, field := range t.Fields {
+		type group struct {
+			key   string
+			value float64
+		}
+		var groups []group
+		for key, metrics := range t.cache {
+			var values []float64
+			for _, m := range metrics {
+				if v, ok := toFloat(m.Fields()[field]); ok {
+					values = append(values, v)
+				}
+			}
+			if len(values) > 0 {
+				groups = append(groups, group{key, t.aggregate(values)})
+			}
+		}
+
+		sort.Slice(groups, func(i, j int) bool {
+			if groups[i].value == groups[j].value {
+				return groups[i].key < groups[j].key
+			}
+			if t.Bottomk {
+				return groups[i].value < groups[j].value
+			}
+			return groups[i].value > groups[j].value
+		})
+
+		for i, g := range groups {
+			if i == t.K {
+				break
+			}
+			if rank, ok := ranks[g.key]; !ok || i+1 < rank {
+				ranks[g.key] = i + 1
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(ranks))
+	for key := range ranks {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if ranks[keys[i]] == ranks[keys[j]] {
+			return keys[i] < keys[j]
+		}
+		return ranks[keys[i]] < ranks[keys[j]]
+	})
+
+	out := []telegraf.Metric{}
+	for _, key := range keys {
+		for _, m := range t.cache[key] {
+			if t.AddRankTag != "" {
+				m.AddTag(t.AddRankTag, strconv.Itoa(ranks[key]))
+			}
+			out = append(out, m)
+		}
+	}
+	for key, metrics := range t.cache {
+		if _, ok := ranks[key]; !ok {
+			for _, m := range metrics {
+				m.Drop()
+			}
+		}
+	}
+
+	t.cache = make(map[string][]telegraf.Metric)
+	return out
+}
+
+func sum(values []float64) float64 {
+	var s float64
+	for _, v := range values {
+		s += v
+	}
+	return s
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case float64:
+		return value, true
+	case int64:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	}
+	return 0, false
+}
+
+func init() {
+	processors.Add("topk", func() telegraf.Processor {
+		return New()
+	})
+}
@@ -0,0 +1,196 @@
+package topk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(process string, value interface{}) telegraf.Metric {
+	m, _ := metric.New("procstat",
+		map[string]string{"process_name": process, "host": "localhost"},
+		map[string]interface{}{"cpu_usage": value, "memory_rss": int64(1)},
+		time.Now(),
+	)
+	return m
+}
+
+// expire makes the next call to Apply or Flush push the buffered metrics.
+func expire(t *TopK) {
+	t.lastAggregation = time.Now().Add(-t.Period.Duration)
+}
+
+func names(metrics []telegraf.Metric) []string {
+	var names []string
+	for _, m := range metrics {
+		names = append(names, m.Tags()["process_name"])
+	}
+	return names
+}
+
+func TestBuffersUntilPeriod(t *testing.T) {
+	topk := New()
+	topk.Fields = []string{"cpu_usage"}
+
+	assert.Empty(t, topk.Apply(newMetric("a", 1.0), newMetric("b", 2.0)))
+	assert.Empty(t, topk.Apply(newMetric("c", 3.0)))
+
+	expire(topk)
+	assert.Equal(t, []string{"c", "b", "a"}, names(topk.Apply()))
+	assert.Empty(t, topk.cache)
+}
+
+func TestAggregations(t *testing.T) {
+	tests := []struct {
+		aggregation string
+		bottomk     bool
+		expected    []string
+	}{
+		{"sum", false, []string{"b", "a"}},
+		{"mean", false, []string{"c", "b"}},
+		{"max", false, []string{"a", "c"}},
+		{"min", false, []string{"c", "b"}},
+		{"min", true, []string{"a", "b"}},
+		{"sum", true, []string{"c", "a"}},
+	}
+
+	for _, tt := range tests {
+		topk := New()
+		topk.K = 2
+		topk.GroupBy = []string{"process_name"}
+		topk.Fields = []string{"cpu_usage"}
+		topk.Aggregation = tt.aggregation
+		topk.Bottomk = tt.bottomk
+
+		topk.Apply(
+			newMetric("a", 9.0), newMetric("a", 0.0), newMetric("a", 0.0),
+			newMetric("b", 5.0), newMetric("b", int64(5)),
+			newMetric("c", 8.0),
+		)
+		expire(topk)
+		metrics := topk.Apply()
+
+		var groups []string
+		for _, name := range names(metrics) {
+			if len(groups) == 0 || groups[len(groups)-1] != name {
+				groups = append(groups, name)
+			}
+		}
+		assert.Equal(t, tt.expected, groups, tt.aggregation)
+	}
+}
+
+func TestGroupByTags(t *testing.T) {
+	topk := New()
+	topk.K = 1
+	topk.GroupBy = []string{"host"}
+	topk.Fields = []string{"cpu_usage"}
+	topk.Aggregation = "sum"
+
+	m, _ := metric.New("procstat",
+		map[string]string{"process_name": "d", "host": "other"},
+		map[string]interface{}{"cpu_usage": 5.0},
+		time.Now(),
+	)
+	topk.Apply(newMetric("a", 1.0), newMetric("b", 2.0), newMetric("c", 3.0), m)
+	expire(topk)
+
+	// all processes of localhost are in one group, that outranks the
+	// other host
+	assert.Equal(t, []string{"a", "b", "c"}, names(topk.Apply()))
+}
+
+func TestRankTag(t *testing.T) {
+	topk := New()
+	topk.K = 1
+	topk.Fields = []string{"cpu_usage", "memory_rss"}
+	topk.AddRankTag = "rank"
+
+	m, _ := metric.New("procstat",
+		map[string]string{"process_name": "d"},
+		map[string]interface{}{"cpu_usage": 0.5, "memory_rss": int64(100)},
+		time.Now(),
+	)
+	topk.Apply(newMetric("a", 1.0), newMetric("b", 2.0), m)
+	expire(topk)
+	metrics := topk.Apply()
+
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "1", metrics[0].Tags()["rank"])
+	assert.Equal(t, "1", metrics[1].Tags()["rank"])
+	assert.Equal(t, []string{"b", "d"}, names(metrics))
+}
+
+func TestIgnoresMissingFields(t *testing.T) {
+	topk := New()
+	topk.Fields = []string{"cpu_usage"}
+
+	topk.Apply(newMetric("a", "high"), newMetric("b", 2.0))
+	expire(topk)
+	assert.Equal(t, []string{"b"}, names(topk.Apply()))
+}
+
+func TestInvalidConfig(t *testing.T) {
+	var acc testutil.Accumulator
+	topk := New()
+	topk.Aggregation = "median"
+	topk.SetAccumulator(&acc)
+
+	assert.Len(t, topk.Apply(newMetric("a", 1.0)), 1)
+	assert.Len(t, topk.Apply(newMetric("b", 1.0)), 1)
+	assert.Len(t, acc.Errors, 1)
+}
+
+func TestFlushAfterPeriod(t *testing.T) {
+	topk := New()
+	topk.Fields = []string{"cpu_usage"}
+	topk.Period.Duration = 50 * time.Millisecond
+
+	topk.Apply(newMetric("a", 1.0), newMetric("b", 2.0))
+	assert.Empty(t, topk.Flush(false))
+
+	// no metrics arrive during the period
+	time.Sleep(topk.Period.Duration)
+	assert.Equal(t, []string{"b", "a"}, names(topk.Flush(false)))
+	assert.Empty(t, topk.cache)
+	assert.Empty(t, topk.Flush(false))
+}
+
+func TestFlushFinal(t *testing.T) {
+	topk := New()
+	topk.Fields = []string{"cpu_usage"}
+
+	topk.Apply(newMetric("a", 1.0), newMetric("b", 2.0))
+	assert.Empty(t, topk.Flush(false))
+	assert.Equal(t, []string{"b", "a"}, names(topk.Flush(true)))
+	assert.Empty(t, topk.Flush(true))
+}
+
+func TestDropsOtherGroups(t *testing.T) {
+	topk := New()
+	topk.K = 1
+	topk.Fields = []string{"cpu_usage"}
+
+	var delivered []telegraf.TrackingID
+	notify := func(info telegraf.DeliveryInfo) {
+		delivered = append(delivered, info.ID())
+	}
+	a, aID := metric.WithTracking(newMetric("a", 1.0), notify)
+	b, _ := metric.WithTracking(newMetric("b", 2.0), notify)
+
+	topk.Apply(a, b)
+	assert.Empty(t, delivered)
+
+	expire(topk)
+	out := topk.Apply()
+	require.Len(t, out, 1)
+	assert.Equal(t, []telegraf.TrackingID{aID}, delivered)
+
+	out[0].Accept()
+	assert.Len(t, delivered, 2)
+}
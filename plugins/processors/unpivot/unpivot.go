@@ -0,0 +1,80 @@
+package unpivot
+
+import (
+	"log"
+	"sort"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Tag to use for the name.
+  tag_key = "name"
+  ## Field to use for the name of the value.
+  value_key = "value"
+`
+
+type Unpivot struct {
+	TagKey   string `toml:"tag_key"`
+	ValueKey string `toml:"value_key"`
+}
+
+func (p *Unpivot) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Unpivot) Description() string {
+	return "Rotate multi field metric into several single field metrics"
+}
+
+func (p *Unpivot) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
+	results := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		fields := m.Fields()
+		if len(fields) == 1 {
+			// a single field metric is rotated in place
+			for key, value := range fields {
+				m.AddTag(p.TagKey, key)
+				if key != p.ValueKey {
+					m.AddField(p.ValueKey, value)
+					m.RemoveField(key)
+				}
+			}
+			results = append(results, m)
+			continue
+		}
+
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		base := m.Tags()
+		for _, key := range keys {
+			tags := make(map[string]string, len(base)+1)
+			for k, v := range base {
+				tags[k] = v
+			}
+			tags[p.TagKey] = key
+
+			n, err := metric.New(m.Name(), tags,
+				map[string]interface{}{p.ValueKey: fields[key]}, m.Time(), m.Type())
+			if err != nil {
+				log.Printf("E! [processors.unpivot] Error creating metric: %s", err)
+				continue
+			}
+			n.SetAggregate(m.IsAggregate())
+			results = append(results, n)
+		}
+	}
+	return results
+}
+
+func init() {
+	processors.Add("unpivot", func() telegraf.Processor {
+		return &Unpivot{}
+	})
+}
@@ -0,0 +1,65 @@
+package unpivot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New("cpu", tags, fields, time.Unix(0, 0))
+	return m
+}
+
+func TestUnpivot(t *testing.T) {
+	unpivot := &Unpivot{TagKey: "name", ValueKey: "value"}
+
+	out := unpivot.Apply(newMetric(
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"idle": 42.0, "user": 7.0, "system": int64(3)},
+	))
+
+	require.Len(t, out, 3)
+	expected := []struct {
+		name  string
+		value interface{}
+	}{
+		{"idle", 42.0},
+		{"system", int64(3)},
+		{"user", 7.0},
+	}
+	for i, e := range expected {
+		assert.Equal(t, "cpu", out[i].Name())
+		assert.Equal(t, map[string]string{"host": "localhost", "name": e.name}, out[i].Tags())
+		assert.Equal(t, map[string]interface{}{"value": e.value}, out[i].Fields())
+		assert.Equal(t, int64(0), out[i].UnixNano())
+	}
+}
+
+func TestUnpivotSingleField(t *testing.T) {
+	unpivot := &Unpivot{TagKey: "name", ValueKey: "value"}
+
+	in := newMetric(nil, map[string]interface{}{"idle": 42.0})
+	out := unpivot.Apply(in)
+
+	require.Len(t, out, 1)
+	assert.True(t, in == out[0])
+	assert.Equal(t, map[string]string{"name": "idle"}, out[0].Tags())
+	assert.Equal(t, map[string]interface{}{"value": 42.0}, out[0].Fields())
+}
+
+func TestUnpivotKeepsAggregate(t *testing.T) {
+	unpivot := &Unpivot{TagKey: "name", ValueKey: "value"}
+
+	in := newMetric(nil, map[string]interface{}{"min": 1.0, "max": 2.0})
+	in.SetAggregate(true)
+	out := unpivot.Apply(in)
+
+	require.Len(t, out, 2)
+	assert.True(t, out[0].IsAggregate())
+	assert.True(t, out[1].IsAggregate())
+}
@@ -10,3 +10,26 @@ type Processor interface {
 	// Apply the filter to the given metric
 	Apply(in ...Metric) []Metric
 }
+
+// ErrorReportingProcessor is a Processor which reports errors, ie values it
+// fails to convert, to an accumulator of its own like inputs do.
+type ErrorReportingProcessor interface {
+	Processor
+
+	// SetAccumulator gives the processor the accumulator to report errors
+	// to. It is called before the processor is first applied.
+	SetAccumulator(Accumulator)
+}
+
+// BufferingProcessor is a Processor which holds on to metrics, and passes
+// them on from a later call instead of returning them right away. It takes
+// over the delivery of the metrics it holds: each of them must eventually be
+// returned, or released with Drop.
+type BufferingProcessor interface {
+	Processor
+
+	// Flush returns the held metrics which are due to be passed on. The
+	// agent calls it every second, and with final set when it stops, in
+	// which case all of them must be returned or dropped.
+	Flush(final bool) []Metric
+}
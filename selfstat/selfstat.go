@@ -87,8 +87,33 @@ func RegisterTiming(measurement, field string, tags map[string]string) Stat {
 	})
 }
 
+// Unregister removes the given stat from the selfstat registry, so that it is
+// no longer returned by Metrics(), ie once the plugin it belongs to is gone.
+func Unregister(s Stat) {
+	registry.unregister(s)
+}
+
 // Metrics returns all registered stats as telegraf metrics.
 func Metrics() []telegraf.Metric {
+	return collect(Stat.Get)
+}
+
+// Snapshot returns all registered stats as telegraf metrics, like Metrics,
+// but without clearing the timings accumulated since the last call to Get().
+// It is meant for looking at the stats without disturbing their collection.
+func Snapshot() []telegraf.Metric {
+	return collect(peek)
+}
+
+// peek returns the value of the stat, leaving timings in place.
+func peek(s Stat) int64 {
+	if ts, ok := s.(*timingStat); ok {
+		return ts.peek()
+	}
+	return s.Get()
+}
+
+func collect(get func(Stat) int64) []telegraf.Metric {
 	registry.mu.Lock()
 	now := time.Now()
 	metrics := make([]telegraf.Metric, len(registry.stats))
@@ -104,7 +129,7 @@ func Metrics() []telegraf.Metric {
 					tags = stat.Tags()
 					name = stat.Name()
 				}
-				fields[fieldname] = stat.Get()
+				fields[fieldname] = get(stat)
 				j++
 			}
 			metric, err := metric.New(name, tags, fields, now)
@@ -117,7 +142,7 @@ func Metrics() []telegraf.Metric {
 		}
 	}
 	registry.mu.Unlock()
-	return metrics
+	return metrics[:i]
 }
 
 type rgstry struct {
@@ -143,6 +168,19 @@ func (r *rgstry) register(s Stat) Stat {
 	}
 }
 
+func (r *rgstry) unregister(s Stat) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats, ok := r.stats[s.Key()]
+	if !ok || stats[s.FieldName()] != s {
+		return
+	}
+	delete(stats, s.FieldName())
+	if len(stats) == 0 {
+		delete(r.stats, s.Key())
+	}
+}
+
 func key(measurement string, tags map[string]string) uint64 {
 	h := fnv.New64a()
 	h.Write([]byte(measurement))
@@ -7,6 +7,7 @@ import (
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -219,3 +220,43 @@ func TestRegisterMetricsAndVerify(t *testing.T) {
 		},
 	)
 }
+
+func TestSnapshotKeepsTimings(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+
+	s := RegisterTiming("test_snapshot", "test_field_ns", map[string]string{})
+	s.Incr(10)
+	s.Incr(20)
+
+	metrics := Snapshot()
+	require.Len(t, metrics, 1)
+	assert.Equal(t, int64(15), metrics[0].Fields()["test_field_ns"])
+
+	// the timings are still there for the next call to Get()
+	s.Incr(30)
+	assert.Equal(t, int64(20), s.Get())
+}
+
+func TestUnregister(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+
+	s1 := Register("test_unregister", "test_field1", map[string]string{"test": "foo"})
+	s2 := Register("test_unregister", "test_field2", map[string]string{"test": "foo"})
+	s1.Incr(1)
+	s2.Incr(2)
+
+	Unregister(s1)
+	metrics := Metrics()
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{"test_field2": int64(2)}, metrics[0].Fields())
+
+	// registering again gives a new stat
+	s1 = Register("test_unregister", "test_field1", map[string]string{"test": "foo"})
+	assert.Equal(t, int64(0), s1.Get())
+
+	Unregister(s1)
+	Unregister(s2)
+	assert.Empty(t, Metrics())
+}
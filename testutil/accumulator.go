@@ -36,6 +36,9 @@ type Accumulator struct {
 	Discard  bool
 	Errors   []error
 	debug    bool
+
+	delivered  chan telegraf.DeliveryInfo
+	trackingID uint64
 }
 
 func (a *Accumulator) NMetrics() uint64 {
@@ -153,6 +156,58 @@ func (a *Accumulator) AddError(err error) {
 	a.Unlock()
 }
 
+// WithTracking returns the Accumulator itself. Tracked metrics added to it are
+// reported as delivered right away.
+func (a *Accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	a.Lock()
+	defer a.Unlock()
+	if a.delivered == nil {
+		a.delivered = make(chan telegraf.DeliveryInfo, maxTracked)
+	}
+	return a
+}
+
+func (a *Accumulator) AddTrackingMetric(m telegraf.Metric) telegraf.TrackingID {
+	a.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	return a.addDelivery()
+}
+
+func (a *Accumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	a.AddMetrics(group)
+	return a.addDelivery()
+}
+
+func (a *Accumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.deliveredChan()
+}
+
+func (a *Accumulator) deliveredChan() chan telegraf.DeliveryInfo {
+	a.Lock()
+	defer a.Unlock()
+	if a.delivered == nil {
+		a.delivered = make(chan telegraf.DeliveryInfo, 1000)
+	}
+	return a.delivered
+}
+
+func (a *Accumulator) addDelivery() telegraf.TrackingID {
+	id := telegraf.TrackingID(atomic.AddUint64(&a.trackingID, 1))
+	a.deliveredChan() <- &deliveryInfo{id: id}
+	return id
+}
+
+type deliveryInfo struct {
+	id telegraf.TrackingID
+}
+
+func (r *deliveryInfo) ID() telegraf.TrackingID {
+	return r.id
+}
+
+func (r *deliveryInfo) Delivered() bool {
+	return true
+}
+
 func (a *Accumulator) SetPrecision(precision, interval time.Duration) {
 	return
 }